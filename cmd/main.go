@@ -1,67 +1,294 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 
 	"calculator/core"
 	"calculator/storage"
 	"calculator/ui"
 )
 
+// Коды завершения процесса
+const (
+	exitOK    = 0
+	exitError = 1 // ошибка при выполнении команды
+	exitUsage = 2 // неверные аргументы командной строки
+)
+
 func main() {
-	store := storage.NewFileStorage("calculator_state.json")
-	numVars, strVars, history, err := store.Load()
-	if err != nil {
-		log.Printf("Не удалось загрузить состояние: %v", err)
-		numVars = make(map[string]float64)
-		strVars = make(map[string]string)
-		history = []string{}
+	expr := flag.String("e", "", "выполнить одну команду, вывести результат и выйти")
+	statePath := flag.String("state", "calculator_state.json", "файл состояния (пустая строка — не загружать и не сохранять)")
+	outputDir := flag.String("outdir", ".", "каталог для графиков, сохранённых через export(...)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Использование: %s [-e команда] [-state файл] [-outdir каталог] [скрипт]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Без аргументов команды читаются со стандартного ввода.")
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	interpreter := core.NewInterpreter(numVars, strVars, history)
-	console := ui.NewConsoleUI()
+	if flag.NArg() > 1 || (*expr != "" && flag.NArg() > 0) {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
 
-	// Выводим историю при запуске
-	if len(history) > 0 {
-		console.PrintHistory(history)
+	app := newApp(*statePath)
+	app.console.SetOutputDir(*outputDir)
+	switch {
+	case *expr != "":
+		os.Exit(app.runCommand(*expr))
+	case flag.NArg() == 1:
+		os.Exit(app.runScript(flag.Arg(0)))
+	default:
+		os.Exit(app.runConsole())
 	}
+}
 
-	for {
-		cmd, err := console.ReadCommand()
+type app struct {
+	interpreter *core.Interpreter
+	store       *storage.FileStorage // nil — состояние не сохраняется
+	console     *ui.ConsoleUI
+	quit        bool // выполнена команда exit
+}
+
+func newApp(statePath string) *app {
+	state := storage.NewState()
+	var store *storage.FileStorage
+	if statePath != "" {
+		store = storage.NewFileStorage(statePath)
+		loaded, err := store.Load()
 		if err != nil {
-			break
+			log.Printf("Не удалось загрузить состояние: %v", err)
+		} else {
+			state = loaded
 		}
+	}
+
+	interpreter := core.NewInterpreter(state.Variables, state.History)
+	if err := interpreter.LoadFunctions(state.Functions); err != nil {
+		// Определения остаются в состоянии и сохраняются без изменений
+		log.Printf("Не удалось загрузить функции:\n%v", err)
+	}
 
-		if cmd == "exit" {
-			break
+	a := &app{
+		interpreter: interpreter,
+		store:       store,
+		console:     ui.NewConsoleUI(),
+	}
+	for _, cmd := range a.commands() {
+		if err := interpreter.RegisterCommand(cmd); err != nil {
+			log.Fatal(err)
 		}
+	}
+	return a
+}
 
-		if cmd == "history" {
-			console.PrintHistory(interpreter.GetHistory())
-			continue
+// commands — команды интерфейса: выход и настройки вывода
+func (a *app) commands() []core.Command {
+	return []core.Command{
+		&core.SimpleCommand{
+			Names:       []string{"exit", "quit"},
+			Syntax:      "exit",
+			Description: "завершить работу",
+			Run: func(args string) (core.Value, error) {
+				a.quit = true
+				return nil, nil
+			},
+		},
+		&core.SimpleCommand{
+			Names:       []string{":base"},
+			Syntax:      ":base <2|8|10|16>",
+			Description: "система счисления для вывода целых чисел",
+			Run:         a.setBase,
+		},
+		&core.SimpleCommand{
+			Names:       []string{":outdir"},
+			Syntax:      ":outdir <каталог>",
+			Description: "каталог для графиков, сохранённых через export(...)",
+			Run:         a.setOutputDir,
+		},
+		&core.SimpleCommand{
+			Names:       []string{":chart"},
+			Syntax:      ":chart <параметр> <значение>",
+			Description: "оформление графиков в файлах: title <текст>, grid on|off, legend on|off",
+			Run:         a.setChart,
+		},
+	}
+}
+
+// runCommand выполняет одну команду из флага -e
+func (a *app) runCommand(cmd string) int {
+	if err := a.execute(cmd); err != nil {
+		a.console.PrintError(err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runScript выполняет файл построчно и останавливается на первой ошибке
+func (a *app) runScript(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		a.console.PrintError(err)
+		return exitUsage
+	}
+	defer file.Close()
+
+	return a.run(ui.NewReaderUI(file, false), path)
+}
+
+// runConsole работает в интерактивном режиме, если стандартный ввод — терминал,
+// и как фильтр в конвейере в противном случае
+func (a *app) runConsole() int {
+	// Выводим историю при запуске
+	if a.console.Interactive() {
+		if history := a.interpreter.GetHistory(); len(history) > 0 {
+			a.console.PrintHistory(history)
 		}
+	}
+	return a.run(a.console, "")
+}
 
-		result, err := interpreter.Execute(cmd)
+// run читает и выполняет команды до конца ввода или команды exit.
+// Для скрипта (source != "") выполнение прерывается на первой ошибке,
+// при чтении из конвейера ошибка отражается только в коде завершения.
+func (a *app) run(input *ui.ConsoleUI, source string) int {
+	status := exitOK
+	for {
+		cmd, err := input.ReadCommand()
 		if err != nil {
-			if err.Error() == "history" {
-				console.PrintHistory(interpreter.GetHistory())
-				continue
+			if err != io.EOF {
+				a.console.PrintError(err)
+				return exitError
 			}
-			console.PrintError(err)
+			return status
+		}
+		if cmd == "" || strings.HasPrefix(cmd, "#") {
 			continue
 		}
+		line := input.Line()
 
-		// Вывод результата
-		switch v := result.(type) {
-		case string:
-			console.PrintStringResult(v)
-		case float64:
-			console.PrintResult(v)
+		// Многострочный блок: дочитываем, пока не закроются все '{'
+		for core.IncompleteInput(cmd) {
+			more, err := input.ReadContinuation()
+			if err != nil {
+				a.console.PrintError(locate(source, line, errors.New("не закрыт блок '{'")))
+				return exitError
+			}
+			cmd += "\n" + more
 		}
 
-		// Сохраняем состояние
-		if err := store.Save(interpreter.GetVariables(), interpreter.GetStringVariables(), interpreter.GetHistory()); err != nil {
-			console.PrintError(err)
+		if err := a.execute(cmd); err != nil {
+			a.console.PrintError(locate(source, line, err))
+			if source != "" {
+				return exitError
+			}
+			if !input.Interactive() {
+				status = exitError
+			}
+		}
+		if a.quit {
+			return status
+		}
+	}
+}
+
+func locate(source string, line int, err error) error {
+	if source == "" {
+		return err
+	}
+	// Ошибка разбора многострочного блока указывает на строку внутри блока:
+	// переводим её в строку файла и оставляем в сообщении только столбец
+	var parseErr *core.ParseError
+	if errors.As(err, &parseErr) {
+		located := *parseErr
+		located.Line = 1
+		return fmt.Errorf("%s:%d: %w", source, line+parseErr.Line-1, &located)
+	}
+	return fmt.Errorf("%s:%d: %w", source, line, err)
+}
+
+// execute выполняет команду, выводит результат и сохраняет состояние
+func (a *app) execute(cmd string) error {
+	result, err := a.interpreter.Execute(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := a.console.PrintValue(result); err != nil {
+		return err
+	}
+
+	return a.save()
+}
+
+// Система счисления вывода и оформление графиков — настройки интерфейса, а не интерпретатора
+
+// setBase обрабатывает команду ":base <2|8|10|16>"
+func (a *app) setBase(args string) (core.Value, error) {
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		return nil, errors.New("использование: :base <2|8|10|16>")
+	}
+	base, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("некорректная система счисления: %s", fields[0])
+	}
+	return nil, a.console.SetBase(base)
+}
+
+// setOutputDir обрабатывает команду ":outdir <каталог>"
+func (a *app) setOutputDir(args string) (core.Value, error) {
+	if len(strings.Fields(args)) != 1 {
+		return nil, errors.New("использование: :outdir <каталог>")
+	}
+	a.console.SetOutputDir(args)
+	return nil, nil
+}
+
+// setChart обрабатывает команды ":chart title <текст>", ":chart grid on|off", ":chart legend on|off"
+func (a *app) setChart(args string) (core.Value, error) {
+	const usage = "использование: :chart title <текст> | :chart grid on|off | :chart legend on|off"
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil, errors.New(usage)
+	}
+	chart := a.console.Chart()
+	switch fields[0] {
+	case "title":
+		// Заголовок берётся целиком, с пробелами; пустой — без заголовка
+		chart.Title = strings.TrimSpace(strings.TrimPrefix(args, "title"))
+	case "grid", "legend":
+		if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+			return nil, errors.New(usage)
+		}
+		if fields[0] == "grid" {
+			chart.Grid = fields[1] == "on"
+		} else {
+			chart.Legend = fields[1] == "on"
 		}
+	default:
+		return nil, errors.New(usage)
+	}
+	a.console.SetChart(chart)
+	return nil, nil
+}
+
+// Сохраняем состояние
+func (a *app) save() error {
+	if a.store == nil {
+		return nil
+	}
+	state := &storage.State{
+		Variables: a.interpreter.Variables(),
+		Functions: a.interpreter.GetFunctions(),
+		History:   a.interpreter.GetHistory(),
 	}
-}
\ No newline at end of file
+	return a.store.Save(state)
+}
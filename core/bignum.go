@@ -0,0 +1,277 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DefaultPrecision — число знаков после запятой для неточных результатов в режиме big
+const DefaultPrecision = 30
+
+// exact возвращает литерал в виде точной дроби: 0.1 — это ровно 1/10
+func (n *NumberNode) exact() (*big.Rat, error) {
+	r := new(big.Rat)
+	if n.Text == "" {
+		return r.SetFloat64(n.Val), nil
+	}
+	if _, ok := r.SetString(n.Text); !ok {
+		return nil, fmt.Errorf("некорректное число: %s", n.Text)
+	}
+	return r, nil
+}
+
+// toRat приводит число к точной дроби; бесконечность и NaN не приводятся
+func toRat(v Value) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case Rational:
+		return n.Rat, true
+	case Number:
+		r := new(big.Rat).SetFloat64(float64(n))
+		return r, r != nil
+	case Integer:
+		return new(big.Rat).SetInt64(int64(n)), true
+	default:
+		return nil, false
+	}
+}
+
+// BinaryOp — точная арифметика; целые и дробные операнды приводятся к дробям.
+// Результат получает большую из точностей операндов.
+func (r Rational) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	precision := r.precision()
+	switch o := other.(type) {
+	case Rational:
+		precision = max(precision, o.precision())
+	case Number, Integer:
+	default:
+		return nil, ErrUnsupported
+	}
+	left, right := operands(r, other, reversed)
+	if isBitwise(op) {
+		return bitwiseOp(left, op, right)
+	}
+	x, ok1 := toRat(left)
+	y, ok2 := toRat(right)
+	if !ok1 || !ok2 {
+		// Бесконечность и NaN дробью не записываются, с ними считаем в float64
+		xf, _ := toFloat(left)
+		yf, _ := toFloat(right)
+		return floatArithmetic(xf, op, yf)
+	}
+	result, err := bigArithmetic(x, op, y, precision)
+	if err != nil {
+		return nil, err
+	}
+	return Rational{Rat: result, Digits: precision}, nil
+}
+
+func (r Rational) Compare(other Value) (int, error) {
+	switch other.(type) {
+	case Rational, Number, Integer:
+	default:
+		return 0, ErrUnsupported
+	}
+	if o, ok := toRat(other); ok {
+		return r.Rat.Cmp(o), nil
+	}
+	x, _ := toFloat(other)
+	f, _ := r.Rat.Float64()
+	return compareFloats(f, x), nil
+}
+
+// precisionBits переводит число десятичных знаков в точность big.Float
+func precisionBits(digits int) uint {
+	return uint(float64(digits)*math.Log2(10)) + 64
+}
+
+func bigArithmetic(left *big.Rat, op string, right *big.Rat, precision int) (*big.Rat, error) {
+	switch op {
+	case "+":
+		return new(big.Rat).Add(left, right), nil
+	case "-":
+		return new(big.Rat).Sub(left, right), nil
+	case "*":
+		return new(big.Rat).Mul(left, right), nil
+	case "/":
+		if right.Sign() == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return new(big.Rat).Quo(left, right), nil
+	case "//":
+		if right.Sign() == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return ratFloor(new(big.Rat).Quo(left, right)), nil
+	case "%":
+		if right.Sign() == 0 {
+			return nil, errors.New("деление по модулю на ноль")
+		}
+		// Остаток со знаком делителя, как у "//"
+		q := ratFloor(new(big.Rat).Quo(left, right))
+		return new(big.Rat).Sub(left, q.Mul(q, right)), nil
+	case "^", "**":
+		return ratPow(left, right, precision)
+	default:
+		return nil, fmt.Errorf("неизвестный оператор: %s", op)
+	}
+}
+
+func ratFloor(r *big.Rat) *big.Rat {
+	// big.Int.Div — евклидово деление, при положительном знаменателе это floor
+	q := new(big.Int).Div(r.Num(), r.Denom())
+	return new(big.Rat).SetInt(q)
+}
+
+func ratCeil(r *big.Rat) *big.Rat {
+	f := ratFloor(r)
+	if f.Cmp(r) == 0 {
+		return f
+	}
+	return f.Add(f, big.NewRat(1, 1))
+}
+
+// ratPow возводит в степень: целая степень считается точно,
+// дробная p/q — через корень степени q с заданной точностью
+func ratPow(base, exp *big.Rat, precision int) (*big.Rat, error) {
+	if exp.IsInt() {
+		return ratIntPow(base, exp.Num())
+	}
+
+	q := exp.Denom()
+	if !q.IsInt64() || q.Int64() > 1<<20 {
+		return nil, errors.New("слишком сложный дробный показатель степени")
+	}
+	root, err := ratRoot(base, q.Int64(), precision)
+	if err != nil {
+		return nil, err
+	}
+	return ratIntPow(root, exp.Num())
+}
+
+func ratIntPow(base *big.Rat, n *big.Int) (*big.Rat, error) {
+	if n.BitLen() > 32 {
+		return nil, errors.New("слишком большой показатель степени")
+	}
+	if n.Sign() < 0 && base.Sign() == 0 {
+		return nil, errors.New("деление на ноль")
+	}
+
+	abs := new(big.Int).Abs(n)
+	num := new(big.Int).Exp(base.Num(), abs, nil)
+	den := new(big.Int).Exp(base.Denom(), abs, nil)
+	if n.Sign() < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// ratRoot вычисляет корень степени q методом Ньютона в big.Float
+func ratRoot(a *big.Rat, q int64, precision int) (*big.Rat, error) {
+	if a.Sign() == 0 {
+		return new(big.Rat), nil
+	}
+	negative := a.Sign() < 0
+	if negative && q%2 == 0 {
+		return nil, fmt.Errorf("корень чётной степени из отрицательного числа: %s", FormatRat(a, precision))
+	}
+
+	prec := precisionBits(precision)
+	x := new(big.Float).SetPrec(prec).SetRat(a)
+	x.Abs(x)
+
+	// Начальное приближение из float64, дальше — квадратичная сходимость
+	af, _ := x.Float64()
+	guess := math.Pow(af, 1/float64(q))
+	if math.IsInf(guess, 0) || guess == 0 || math.IsNaN(guess) {
+		guess = 1
+	}
+	root := new(big.Float).SetPrec(prec).SetFloat64(guess)
+
+	qf := new(big.Float).SetPrec(prec).SetInt64(q)
+	q1 := new(big.Float).SetPrec(prec).SetInt64(q - 1)
+	eps := new(big.Float).SetPrec(prec).SetMantExp(big.NewFloat(1), -int(prec)+8)
+
+	for i := 0; i < 1000; i++ {
+		// root = ((q-1)*root + x/root^(q-1)) / q
+		pow := floatIntPow(root, q-1, prec)
+		next := new(big.Float).SetPrec(prec).Quo(x, pow)
+		next.Add(next, new(big.Float).SetPrec(prec).Mul(q1, root))
+		next.Quo(next, qf)
+
+		diff := new(big.Float).SetPrec(prec).Sub(next, root)
+		root = next
+		if diff.Abs(diff).Cmp(new(big.Float).Mul(eps, root)) <= 0 {
+			break
+		}
+	}
+
+	result, _ := root.Rat(nil)
+	if negative {
+		result.Neg(result)
+	}
+	return result, nil
+}
+
+func floatIntPow(x *big.Float, n int64, prec uint) *big.Float {
+	result := new(big.Float).SetPrec(prec).SetInt64(1)
+	base := new(big.Float).SetPrec(prec).Set(x)
+	for n > 0 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+		n >>= 1
+	}
+	return result
+}
+
+// FormatRat выводит дробь десятичной записью: короткие конечные дроби — точно,
+// остальные — с округлением до заданного числа знаков после запятой
+func FormatRat(r *big.Rat, digits int) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	if decimals, ok := terminatingDecimals(r.Denom()); ok && decimals <= digits {
+		return r.FloatString(decimals)
+	}
+
+	s := r.FloatString(digits)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// terminatingDecimals проверяет, что знаменатель вида 2^a*5^b,
+// и возвращает число знаков конечной десятичной дроби max(a, b)
+func terminatingDecimals(den *big.Int) (int, bool) {
+	d := new(big.Int).Set(den)
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	twos, fives := 0, 0
+	for {
+		if mod.Mod(d, two).Sign() != 0 {
+			break
+		}
+		d.Quo(d, two)
+		twos++
+	}
+	for {
+		if mod.Mod(d, five).Sign() != 0 {
+			break
+		}
+		d.Quo(d, five)
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
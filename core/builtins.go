@@ -0,0 +1,301 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+)
+
+// Встроенные константы. Их нельзя переопределить присваиванием.
+var constants = map[string]Value{
+	"pi":    Number(math.Pi),
+	"e":     Number(math.E),
+	"true":  Bool(true),
+	"false": Bool(false),
+	"null":  Null{},
+}
+
+// Builtin описывает встроенную функцию.
+// MaxArgs = -1 означает произвольное число аргументов.
+type Builtin struct {
+	MinArgs int
+	MaxArgs int
+	Fn      func(args []Value) (Value, error)
+	// Exact — необязательная точная реализация для режима big
+	Exact func(args []*big.Rat, precision int) (Value, error)
+	// Complex — необязательная реализация для комплексного аргумента
+	Complex func(z complex128) (Value, error)
+}
+
+// Реестр всех встроенных функций, собирается из тематических групп
+var builtins = make(map[string]*Builtin)
+
+// specialForm получает аргументы невычисленными: diff(x^2, x) работает с выражением, а не с числом
+type specialForm func(env *Env, args []Node) (Value, error)
+
+// Реестр специальных форм; они проверяются раньше встроенных функций
+var specialForms = make(map[string]specialForm)
+
+func init() {
+	for _, group := range []map[string]*Builtin{mathBuiltins, stringBuiltins, baseBuiltins, intBuiltins, complexBuiltins, listBuiltins, dateBuiltins, statsBuiltins, valueBuiltins} {
+		for name, fn := range group {
+			builtins[name] = fn
+		}
+	}
+	for _, group := range []map[string]specialForm{symbolicForms, solverForms, calculusForms, plotForms} {
+		for name, form := range group {
+			specialForms[name] = form
+		}
+	}
+}
+
+var mathBuiltins = map[string]*Builtin{
+	// Корень и логарифм отрицательного числа — комплексные: sqrt(-4) = 2i
+	"sqrt": {MinArgs: 1, MaxArgs: 1, Fn: builtinSqrt, Complex: complexFunc(cmplx.Sqrt),
+		Exact: func(args []*big.Rat, precision int) (Value, error) {
+			if args[0].Sign() < 0 {
+				f, _ := args[0].Float64()
+				return Complex(complex(0, math.Sqrt(-f))), nil
+			}
+			root, err := ratRoot(args[0], 2, precision)
+			if err != nil {
+				return nil, err
+			}
+			return Rational{Rat: root, Digits: precision}, nil
+		}},
+	"log": {MinArgs: 1, MaxArgs: 1, Fn: builtinLog, Complex: func(z complex128) (Value, error) {
+		if z == 0 {
+			return nil, errors.New("log: логарифм нуля не определён")
+		}
+		return Complex(cmplx.Log(z)), nil
+	}},
+	"log10": mathFunc("log10", func(x float64) (float64, error) {
+		if x <= 0 {
+			return 0, errors.New("log10: аргумент должен быть положительным")
+		}
+		return math.Log10(x), nil
+	}),
+	"sin": withComplex(mathFunc("sin", wrap(math.Sin)), cmplx.Sin),
+	"cos": withComplex(mathFunc("cos", wrap(math.Cos)), cmplx.Cos),
+	"tan": withComplex(mathFunc("tan", wrap(math.Tan)), cmplx.Tan),
+	"exp": withComplex(mathFunc("exp", wrap(math.Exp)), cmplx.Exp),
+	"abs": withComplexResult(exactFunc(mathFunc("abs", wrap(math.Abs)), func(args []*big.Rat, precision int) (Value, error) {
+		return Rational{Rat: new(big.Rat).Abs(args[0]), Digits: precision}, nil
+	}), func(z complex128) (Value, error) {
+		return Number(cmplx.Abs(z)), nil
+	}),
+	"floor": exactFunc(mathFunc("floor", wrap(math.Floor)), func(args []*big.Rat, precision int) (Value, error) {
+		return Rational{Rat: ratFloor(args[0]), Digits: precision}, nil
+	}),
+	"ceil": exactFunc(mathFunc("ceil", wrap(math.Ceil)), func(args []*big.Rat, precision int) (Value, error) {
+		return Rational{Rat: ratCeil(args[0]), Digits: precision}, nil
+	}),
+	"round": {MinArgs: 1, MaxArgs: 2, Fn: builtinRound},
+	"min":   {MinArgs: 1, MaxArgs: -1, Fn: builtinMin, Exact: exactExtremum(-1)},
+	"max":   {MinArgs: 1, MaxArgs: -1, Fn: builtinMax, Exact: exactExtremum(1)},
+}
+
+type CallNode struct {
+	Name string
+	Args []Node
+}
+
+func (c *CallNode) Value(env *Env) (Value, error) {
+	if form, ok := specialForms[c.Name]; ok {
+		return form(env, c.Args)
+	}
+	return c.callFunction(env)
+}
+
+// callFunction вызывает встроенную или пользовательскую функцию, минуя специальные формы
+func (c *CallNode) callFunction(env *Env) (Value, error) {
+	fn, ok := builtins[c.Name]
+	if !ok {
+		if userFn, ok := env.functions[c.Name]; ok {
+			return userFn.Call(env, c.Args)
+		}
+		return nil, fmt.Errorf("неизвестная функция: %s", c.Name)
+	}
+
+	if len(c.Args) < fn.MinArgs || (fn.MaxArgs >= 0 && len(c.Args) > fn.MaxArgs) {
+		return nil, fmt.Errorf("функция %s ожидает %s, получено %d", c.Name, arityString(fn), len(c.Args))
+	}
+
+	args := make([]Value, len(c.Args))
+	for i, arg := range c.Args {
+		val, err := arg.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+
+	if fn.Complex != nil && len(args) == 1 {
+		if z, ok := args[0].(Complex); ok {
+			return fn.Complex(complex128(z))
+		}
+	}
+	if env.bigMode && fn.Exact != nil {
+		if rats, ok := ratArgs(args); ok {
+			return fn.Exact(rats, env.precision)
+		}
+	}
+	return fn.Fn(args)
+}
+
+func ratArgs(args []Value) ([]*big.Rat, bool) {
+	rats := make([]*big.Rat, len(args))
+	for i, arg := range args {
+		r, ok := toRat(arg)
+		if !ok {
+			return nil, false
+		}
+		rats[i] = r
+	}
+	return rats, true
+}
+
+func arityString(fn *Builtin) string {
+	switch {
+	case fn.MaxArgs < 0:
+		return fmt.Sprintf("не менее %d аргументов", fn.MinArgs)
+	case fn.MinArgs == fn.MaxArgs:
+		return fmt.Sprintf("аргументов: %d", fn.MinArgs)
+	default:
+		return fmt.Sprintf("от %d до %d аргументов", fn.MinArgs, fn.MaxArgs)
+	}
+}
+
+// toFloat приводит значение к числу, если это возможно
+func toFloat(v Value) (float64, bool) {
+	switch n := v.(type) {
+	case Number:
+		return float64(n), true
+	case Integer:
+		return float64(n), true
+	case Rational:
+		f, _ := n.Rat.Float64()
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+func floatArgs(name string, args []Value) ([]float64, error) {
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		n, ok := toFloat(arg)
+		if !ok {
+			return nil, fmt.Errorf("%s: аргумент %d должен быть числом", name, i+1)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func wrap(f func(float64) float64) func(float64) (float64, error) {
+	return func(x float64) (float64, error) {
+		return f(x), nil
+	}
+}
+
+// mathFunc превращает функцию одного числового аргумента во встроенную функцию
+func mathFunc(name string, f func(float64) (float64, error)) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			x, ok := toFloat(args[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: аргумент должен быть числом", name)
+			}
+			result, err := f(x)
+			if err != nil {
+				return nil, err
+			}
+			return Number(result), nil
+		},
+	}
+}
+
+// exactFunc добавляет встроенной функции точную реализацию
+func exactFunc(fn *Builtin, exact func(args []*big.Rat, precision int) (Value, error)) *Builtin {
+	fn.Exact = exact
+	return fn
+}
+
+// exactExtremum — точные min (sign = -1) и max (sign = 1)
+func exactExtremum(sign int) func(args []*big.Rat, precision int) (Value, error) {
+	return func(args []*big.Rat, precision int) (Value, error) {
+		result := args[0]
+		for _, r := range args[1:] {
+			if r.Cmp(result) == sign {
+				result = r
+			}
+		}
+		return Rational{Rat: result, Digits: precision}, nil
+	}
+}
+
+func builtinSqrt(args []Value) (Value, error) {
+	x, ok := toFloat(args[0])
+	if !ok {
+		return nil, errors.New("sqrt: аргумент должен быть числом")
+	}
+	if x < 0 {
+		return Complex(complex(0, math.Sqrt(-x))), nil
+	}
+	return Number(math.Sqrt(x)), nil
+}
+
+func builtinLog(args []Value) (Value, error) {
+	x, ok := toFloat(args[0])
+	if !ok {
+		return nil, errors.New("log: аргумент должен быть числом")
+	}
+	switch {
+	case x == 0:
+		return nil, errors.New("log: логарифм нуля не определён")
+	case x < 0:
+		return Complex(cmplx.Log(complex(x, 0))), nil
+	}
+	return Number(math.Log(x)), nil
+}
+
+func builtinRound(args []Value) (Value, error) {
+	nums, err := floatArgs("round", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 1 {
+		return Number(math.Round(nums[0])), nil
+	}
+	scale := math.Pow(10, math.Trunc(nums[1]))
+	return Number(math.Round(nums[0]*scale) / scale), nil
+}
+
+func builtinMin(args []Value) (Value, error) {
+	nums, err := statValues("min", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	result := nums[0]
+	for _, n := range nums[1:] {
+		result = math.Min(result, n)
+	}
+	return Number(result), nil
+}
+
+func builtinMax(args []Value) (Value, error) {
+	nums, err := statValues("max", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	result := nums[0]
+	for _, n := range nums[1:] {
+		result = math.Max(result, n)
+	}
+	return Number(result), nil
+}
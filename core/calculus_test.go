@@ -0,0 +1,84 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"x^2 на [0, 3]", func(x float64) float64 { return x * x }, 0, 3, 9},
+		{"x^2 на [3, 0]", func(x float64) float64 { return x * x }, 3, 0, -9},
+		{"x^2 на [2, 2]", func(x float64) float64 { return x * x }, 2, 2, 0},
+		{"sin на [0, π]", math.Sin, 0, math.Pi, 2},
+		{"exp на [0, 1]", math.Exp, 0, 1, math.E - 1},
+		{"1/(1 + x^2) на [-1, 1]", func(x float64) float64 { return 1 / (1 + x*x) }, -1, 1, math.Pi / 2},
+		{"|x| на [-1, 2]", math.Abs, -1, 2, 2.5},
+		{"sqrt на [0, 1]", math.Sqrt, 0, 1, 2.0 / 3},
+		{"1/sqrt(x) на [0, 1]", func(x float64) float64 { return 1 / math.Sqrt(x) }, 0, 1, 2},
+		{"exp(-x^2) на [-10, 10]", func(x float64) float64 { return math.Exp(-x * x) }, -10, 10, math.Sqrt(math.Pi)},
+	}
+	for _, tt := range tests {
+		f := func(x float64) (float64, error) { return tt.f(x), nil }
+		got, err := integrate(f, tt.a, tt.b, DefaultTolerance)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("%s: %.17g, ожидалось %.17g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIntegrateErrors(t *testing.T) {
+	undefined := errors.New("функция не определена")
+	failing := func(x float64) (float64, error) {
+		if x > 0.5 {
+			return 0, undefined
+		}
+		return x, nil
+	}
+	if _, err := integrate(failing, 0, 1, DefaultTolerance); !errors.Is(err, undefined) {
+		t.Errorf("ошибка функции: получено %v, ожидалось %v", err, undefined)
+	}
+
+	// Интеграл 1/x на [-1, 2] расходится: точность не достигается
+	inverse := func(x float64) (float64, error) { return 1 / x, nil }
+	if got, err := integrate(inverse, -1, 2, DefaultTolerance); err == nil {
+		t.Errorf("1/x на [-1, 2]: ожидалась ошибка, получено %g", got)
+	}
+}
+
+func TestSumAndProduct(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"sum(k, k, 1, 10)", "55"},
+		{"prod(k, k, 1, 5)", "120"},
+		{"sum(k, k, 1, 0)", "0"},
+		{"prod(k, k, 1, 0)", "1"},
+		{"sum(2^k, k, 0, 62)", "9223372036854775807"},
+		// Компенсированное суммирование: десять слагаемых 0.1 дают ровно 1
+		{"sum(0.1, k, 1, 10)", "1"},
+		{"sum([1, 2, 3])", "6"},
+		{"prod([1, 2, 3, 4])", "24"},
+		{"sum(1, 2, 3)", "6"},
+	}
+	for _, tt := range tests {
+		v, err := NewInterpreter(nil, nil).Execute(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("%s = %s, ожидалось %s", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Command — команда, которая выполняется вместо вычисления выражения: help, history, curl.
+// Вызывается первым словом ввода или одним из синонимов; Execute получает остаток строки.
+type Command interface {
+	Name() string
+	Aliases() []string
+	Usage() string // как вызывать: "curl <url>"
+	Help() string  // что делает, одной строкой
+	// HasResult сообщает, что команда вычисляет значение, как curl и ask: его можно
+	// присвоить переменной, а сама команда попадает в историю. Настройки, help, history
+	// и exit ничего не вычисляют.
+	HasResult() bool
+	Execute(args string) (Value, error)
+}
+
+// SimpleCommand — команда, заданная функцией; так описаны все стандартные команды
+type SimpleCommand struct {
+	Names       []string // основное имя и синонимы
+	Syntax      string
+	Description string
+	Result      bool // см. Command.HasResult
+	Run         func(args string) (Value, error)
+}
+
+func (c *SimpleCommand) Name() string                       { return c.Names[0] }
+func (c *SimpleCommand) Aliases() []string                  { return c.Names[1:] }
+func (c *SimpleCommand) Usage() string                      { return c.Syntax }
+func (c *SimpleCommand) Help() string                       { return c.Description }
+func (c *SimpleCommand) HasResult() bool                    { return c.Result }
+func (c *SimpleCommand) Execute(args string) (Value, error) { return c.Run(args) }
+
+// Commands — реестр команд по именам и синонимам
+type Commands struct {
+	list   []Command
+	byName map[string]Command
+}
+
+func NewCommands() *Commands {
+	return &Commands{byName: make(map[string]Command)}
+}
+
+// Register добавляет команду; имя или синоним, занятые другой командой, — ошибка
+func (c *Commands) Register(cmd Command) error {
+	names := append([]string{cmd.Name()}, cmd.Aliases()...)
+	for _, name := range names {
+		if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("некорректное имя команды: %q", name)
+		}
+		if _, ok := c.byName[name]; ok {
+			return fmt.Errorf("команда %s уже зарегистрирована", name)
+		}
+	}
+	for _, name := range names {
+		c.byName[name] = cmd
+	}
+	c.list = append(c.list, cmd)
+	return nil
+}
+
+// Find ищет команду по первому слову ввода и возвращает её аргументы.
+// Слово, за которым идёт оператор ("history = 3", "ask * 2"), — операнд выражения, а не команда.
+// Команды-знаки пишутся и слитно с аргументом: "?вопрос".
+func (c *Commands) Find(input string) (Command, string, bool) {
+	trimmed := strings.TrimSpace(input)
+	word, args := trimmed, ""
+	if i := strings.IndexFunc(trimmed, unicode.IsSpace); i >= 0 {
+		word, args = trimmed[:i], strings.TrimSpace(trimmed[i:])
+	}
+	if cmd, ok := c.byName[word]; ok && !isOperand(args) {
+		return cmd, args, true
+	}
+	if r, size := utf8.DecodeRuneInString(trimmed); size > 0 && unicode.IsPunct(r) {
+		if cmd, ok := c.byName[trimmed[:size]]; ok {
+			return cmd, strings.TrimSpace(trimmed[size:]), true
+		}
+	}
+	return nil, "", false
+}
+
+// isOperand сообщает, что после слова идёт продолжение выражения: "= 3", "* 2"
+func isOperand(args string) bool {
+	return args != "" && strings.ContainsRune("=+-*/^%<>!&|.,;)", rune(args[0]))
+}
+
+// List возвращает команды в порядке имён
+func (c *Commands) List() []Command {
+	list := append([]Command(nil), c.list...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Help составляет справку по всем командам или по одной из них
+func (c *Commands) Help(name string) (string, error) {
+	if name != "" {
+		cmd, ok := c.byName[name]
+		if !ok {
+			return "", fmt.Errorf("неизвестная команда: %s", name)
+		}
+		return fmt.Sprintf("%s\n  %s%s", cmd.Usage(), cmd.Help(), aliasesNote(cmd)), nil
+	}
+
+	list := c.List()
+	width := 0
+	for _, cmd := range list {
+		width = max(width, utf8.RuneCountInString(cmd.Usage()))
+	}
+	var sb strings.Builder
+	sb.WriteString("Команды:\n")
+	for _, cmd := range list {
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(cmd.Usage()))
+		fmt.Fprintf(&sb, "  %s%s  %s%s\n", cmd.Usage(), pad, cmd.Help(), aliasesNote(cmd))
+	}
+	sb.WriteString("Остальной ввод вычисляется как выражение: 2^10, x = 5, f(x) = x^2")
+	return sb.String(), nil
+}
+
+func aliasesNote(cmd Command) string {
+	if len(cmd.Aliases()) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (также: %s)", strings.Join(cmd.Aliases(), ", "))
+}
+
+// commandAssignment — сохранение результата команды в переменную: page = curl http://...
+// Присвоить можно только результат команды с HasResult.
+var commandAssignment = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// RegisterCommand добавляет команду интерпретатора, например команду интерфейса
+func (i *Interpreter) RegisterCommand(cmd Command) error {
+	return i.commands.Register(cmd)
+}
+
+// defaultCommands — стандартные команды интерпретатора
+func (i *Interpreter) defaultCommands() []Command {
+	return []Command{
+		&SimpleCommand{
+			Names:       []string{"help"},
+			Syntax:      "help [команда]",
+			Description: "список команд или справка по одной из них",
+			Run: func(args string) (Value, error) {
+				return textResult(i.commands.Help(args))
+			},
+		},
+		&SimpleCommand{
+			Names:       []string{"history"},
+			Syntax:      "history",
+			Description: "последние выполненные команды",
+			Run: func(args string) (Value, error) {
+				if args != "" {
+					return nil, errors.New("использование: history")
+				}
+				return History(i.GetHistory()), nil
+			},
+		},
+		&SimpleCommand{
+			Names:       []string{"curl"},
+			Syntax:      "curl <url>",
+			Description: "GET-запрос; ответ можно сохранить: page = curl <url>",
+			Result:      true,
+			Run: func(args string) (Value, error) {
+				return textResult(i.executeCurl(args))
+			},
+		},
+		&SimpleCommand{
+			Names:       []string{"ask", "?"},
+			Syntax:      "ask <вопрос>",
+			Description: "вопрос ассистенту",
+			Result:      true,
+			Run: func(args string) (Value, error) {
+				if args == "" {
+					return nil, errors.New("использование: ask <вопрос> или ? <вопрос>")
+				}
+				return textResult(i.classifyAndExecute(args))
+			},
+		},
+		settingCommand(":maxiter", ":maxiter <число>", "лимит итераций циклов за одну команду (0 — без ограничения)", i.setMaxIterations),
+		settingCommand(":tol", ":tol <точность>", "точность численных методов, от 0 до 1", i.setTolerance),
+		settingCommand(":mode", ":mode float | big [знаков]", "режим вычислений: float64 или произвольная точность", i.setMode),
+		settingCommand(":fallback", ":fallback off|auto", "передавать ли ассистенту ввод, похожий на текст", i.setFallback),
+	}
+}
+
+// settingCommand — команда настройки: аргументы разбиваются на слова
+func settingCommand(name, usage, help string, set func(args []string) (string, error)) Command {
+	return &SimpleCommand{
+		Names:       []string{name},
+		Syntax:      usage,
+		Description: help,
+		Run: func(args string) (Value, error) {
+			return textResult(set(strings.Fields(args)))
+		},
+	}
+}
+
+// textResult — результат команды, которая возвращает текст
+func textResult(text string, err error) (Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	return String(text), nil
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+)
+
+// ImaginaryNode — мнимый литерал вида 3i или 2.5i
+type ImaginaryNode struct {
+	Val float64
+}
+
+func (n *ImaginaryNode) Value(env *Env) (Value, error) {
+	return Complex(complex(0, n.Val)), nil
+}
+
+func toComplex(v Value) (complex128, bool) {
+	if z, ok := v.(Complex); ok {
+		return complex128(z), true
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, false
+	}
+	return complex(f, 0), true
+}
+
+// BinaryOp — комплексная арифметика; вещественные операнды приводятся к комплексным
+func (z Complex) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	if _, ok := toComplex(other); !ok {
+		return nil, ErrUnsupported
+	}
+	left, right := operands(z, other, reversed)
+	if isBitwise(op) {
+		return bitwiseOp(left, op, right)
+	}
+	l, _ := toComplex(left)
+	r, _ := toComplex(right)
+	return complexArithmetic(l, op, r)
+}
+
+// complexArithmetic выполняет операцию над комплексными числами. Результат с нулевой
+// мнимой частью возвращается вещественным числом: 3i*3i = -9, а не -9+0i
+func complexArithmetic(left complex128, op string, right complex128) (Value, error) {
+	z, err := complexOp(left, op, right)
+	if err != nil {
+		return nil, err
+	}
+	if imag(z) == 0 {
+		return Number(real(z)), nil
+	}
+	return Complex(z), nil
+}
+
+func complexOp(left complex128, op string, right complex128) (complex128, error) {
+	switch op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, errors.New("деление на ноль")
+		}
+		return left / right, nil
+	case "^", "**":
+		// Целую степень считаем умножением, чтобы (2i)^2 было ровно -4
+		if imag(right) == 0 && real(right) == math.Trunc(real(right)) && math.Abs(real(right)) <= 1024 {
+			return complexIntPow(left, int(real(right)))
+		}
+		if left == 0 && real(right) < 0 {
+			return 0, errors.New("деление на ноль")
+		}
+		if imag(left) == 0 && real(left) < 0 && imag(right) == 0 {
+			return negativePow(real(left), real(right)), nil
+		}
+		return cmplx.Pow(left, right), nil
+	default:
+		return 0, fmt.Errorf("операция %s не определена для комплексных чисел", op)
+	}
+}
+
+// negativePow — дробная степень отрицательного числа: главное значение |a|^b·e^(iπb).
+// Углы, кратные π/2, берутся точно, чтобы (-1)^0.5 было ровно 1i.
+func negativePow(base, exp float64) complex128 {
+	r := math.Pow(-base, exp)
+	switch t := math.Mod(exp, 2); t {
+	case 0.5, -1.5:
+		return complex(0, r)
+	case -0.5, 1.5:
+		return complex(0, -r)
+	default:
+		sin, cos := math.Sincos(math.Pi * t)
+		return complex(r*cos, r*sin)
+	}
+}
+
+func complexIntPow(z complex128, n int) (complex128, error) {
+	if n < 0 {
+		if z == 0 {
+			return 0, errors.New("деление на ноль")
+		}
+		z, n = 1/z, -n
+	}
+	result := complex(1, 0)
+	for n > 0 {
+		if n&1 == 1 {
+			result *= z
+		}
+		z *= z
+		n >>= 1
+	}
+	return result, nil
+}
+
+// FormatComplex выводит комплексное число в виде 1+2i, 2i или 3+0i
+func FormatComplex(z complex128) string {
+	re := strconv.FormatFloat(real(z), 'g', -1, 64)
+	im := strconv.FormatFloat(imag(z), 'g', -1, 64)
+	if real(z) == 0 && imag(z) != 0 {
+		return im + "i"
+	}
+	if imag(z) < 0 || math.IsNaN(imag(z)) {
+		return re + im + "i"
+	}
+	return re + "+" + im + "i"
+}
+
+// complexFunc превращает функцию из math/cmplx в реализацию Builtin.Complex
+func complexFunc(f func(complex128) complex128) func(z complex128) (Value, error) {
+	return func(z complex128) (Value, error) {
+		return Complex(f(z)), nil
+	}
+}
+
+// withComplex добавляет встроенной функции комплексный вариант
+func withComplex(fn *Builtin, f func(complex128) complex128) *Builtin {
+	fn.Complex = complexFunc(f)
+	return fn
+}
+
+// withComplexResult — как withComplex, но для функций с произвольным результатом (например, abs)
+func withComplexResult(fn *Builtin, f func(z complex128) (Value, error)) *Builtin {
+	fn.Complex = f
+	return fn
+}
+
+var complexBuiltins = map[string]*Builtin{
+	"re":   complexPart("re", func(z complex128) Value { return Number(real(z)) }),
+	"im":   complexPart("im", func(z complex128) Value { return Number(imag(z)) }),
+	"arg":  complexPart("arg", func(z complex128) Value { return Number(cmplx.Phase(z)) }),
+	"conj": complexPart("conj", func(z complex128) Value { return Complex(cmplx.Conj(z)) }),
+}
+
+// complexPart — функция одного аргумента, принимающая и вещественные числа как комплексные
+func complexPart(name string, f func(z complex128) Value) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			z, ok := toComplex(args[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: аргумент должен быть числом", name)
+			}
+			return f(z), nil
+		},
+	}
+}
@@ -0,0 +1,466 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // база часовых поясов встроена в программу и не зависит от системы
+)
+
+// Суффиксы литералов длительности, записанных слитно с числом: 3d 4h, 1h30m, 250ms.
+// Через пробел те же буквы означают единицы измерения: "5 m" — метры, "5m" — минуты.
+var durationSuffixes = map[string]time.Duration{
+	"w":  7 * 24 * time.Hour,
+	"d":  24 * time.Hour,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+}
+
+// durationSuffix возвращает суффикс длительности, стоящий сразу после числа
+func (l *Lexer) durationSuffix() (string, bool) {
+	end := l.position
+	for end < len(l.input) && isLetter(l.input[end]) {
+		end++
+	}
+	suffix := l.input[l.position:end]
+	_, ok := durationSuffixes[suffix]
+	return suffix, ok
+}
+
+// parseDurationPart разбирает одну часть литерала длительности: "3d", "1.5h"
+func parseDurationPart(text string) (time.Duration, error) {
+	i := strings.IndexFunc(text, func(r rune) bool { return r < 128 && isLetter(byte(r)) })
+	if i <= 0 {
+		return 0, fmt.Errorf("некорректная длительность: %s", text)
+	}
+	x, err := strconv.ParseFloat(strings.ReplaceAll(text[:i], "_", ""), 64)
+	unit, ok := durationSuffixes[text[i:]]
+	if err != nil || !ok {
+		return 0, fmt.Errorf("некорректная длительность: %s", text)
+	}
+	return durationOf(x * float64(unit))
+}
+
+// durationOf проверяет, что число наносекунд помещается в time.Duration
+func durationOf(ns float64) (time.Duration, error) {
+	if math.IsNaN(ns) || math.Abs(ns) >= math.MaxInt64 {
+		return 0, errors.New("длительность вне допустимого диапазона (около ±292 лет)")
+	}
+	return time.Duration(math.Round(ns)), nil
+}
+
+// DurationNode — литерал длительности; соседние части складываются: 3d 4h
+type DurationNode struct {
+	Val time.Duration
+}
+
+func (n *DurationNode) Value(env *Env) (Value, error) {
+	return Duration(n.Val), nil
+}
+
+func (p *Parser) parseDuration() (Node, error) {
+	var total time.Duration
+	for p.currentToken.Type == TokenDuration {
+		part, err := parseDurationPart(p.currentToken.Value)
+		if err != nil {
+			return nil, err
+		}
+		if total, err = durationOf(float64(total) + float64(part)); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+	}
+	return &DurationNode{Val: total}, nil
+}
+
+// FormatDuration записывает длительность так же, как она вводится: 3d 4h 5m 1.5s
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	var parts []string
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}} {
+		if d >= unit.size {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.suffix))
+			d %= unit.size
+		}
+	}
+	if d > 0 {
+		parts = append(parts, strconv.FormatFloat(d.Seconds(), 'f', -1, 64)+"s")
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// FormatTime выводит момент времени с часовым поясом
+func FormatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+// toDuration приводит к длительности time.Duration и величину с размерностью времени: 3 h
+func toDuration(v Value) (time.Duration, bool) {
+	switch val := v.(type) {
+	case Duration:
+		return time.Duration(val), true
+	case Quantity:
+		if val.Unit.dim() != dimTime {
+			return 0, false
+		}
+		d, err := durationOf(val.Value * float64(time.Second))
+		return d, err == nil
+	}
+	return 0, false
+}
+
+// durationResult — длительность из числа наносекунд с проверкой диапазона
+func durationResult(ns float64) (Value, error) {
+	d, err := durationOf(ns)
+	if err != nil {
+		return nil, err
+	}
+	return Duration(d), nil
+}
+
+// durationQuantity представляет длительность величиной в секундах для операций с единицами
+func durationQuantity(d Duration) Quantity {
+	return Quantity{Value: time.Duration(d).Seconds(), Unit: Unit{terms: []unitTerm{{Name: "s", Power: 1}}}}
+}
+
+// dateOperand сообщает, что значение может участвовать в операции с датой или длительностью
+func dateOperand(v Value) bool {
+	switch v.(type) {
+	case Time, Duration, Quantity:
+		return true
+	}
+	_, ok := toFloat(v)
+	return ok
+}
+
+// BinaryOp — сдвиг даты на длительность и разность дат
+func (t Time) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	if !dateOperand(other) {
+		return nil, ErrUnsupported
+	}
+	left, right := operands(t, other, reversed)
+	return dateArithmetic(left, op, right)
+}
+
+// BinaryOp — операции длительностей между собой, с числами и величинами: 100 km / 2h
+func (d Duration) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	if !dateOperand(other) {
+		return nil, ErrUnsupported
+	}
+	left, right := operands(d, other, reversed)
+	return dateArithmetic(left, op, right)
+}
+
+// dateArithmetic выполняет операцию, в которой участвует дата или длительность
+func dateArithmetic(left Value, op string, right Value) (Value, error) {
+	lt, leftIsTime := left.(Time)
+	rt, rightIsTime := right.(Time)
+	ld, leftIsDuration := toDuration(left)
+	rd, rightIsDuration := toDuration(right)
+
+	switch {
+	case leftIsTime && rightIsTime && op == "-":
+		return Duration(time.Time(lt).Sub(time.Time(rt))), nil
+	case leftIsTime && rightIsDuration && (op == "+" || op == "-"):
+		if op == "-" {
+			rd = -rd
+		}
+		return Time(time.Time(lt).Add(rd)), nil
+	case leftIsDuration && rightIsTime && op == "+":
+		return Time(time.Time(rt).Add(ld)), nil
+	case leftIsTime || rightIsTime:
+		return nil, fmt.Errorf("операция %s невозможна между %s и %s", op, left, right)
+	}
+
+	if leftIsDuration && rightIsDuration {
+		switch op {
+		case "+":
+			return durationResult(float64(ld) + float64(rd))
+		case "-":
+			return durationResult(float64(ld) - float64(rd))
+		case "/":
+			if rd == 0 {
+				return nil, errors.New("деление на ноль")
+			}
+			return Number(float64(ld) / float64(rd)), nil
+		case "%":
+			if rd == 0 {
+				return nil, errors.New("деление по модулю на ноль")
+			}
+			return Duration(ld % rd), nil
+		}
+	}
+
+	// Длительность и величина с единицами: 100 km / 2h
+	_, leftIsQuantity := left.(Quantity)
+	_, rightIsQuantity := right.(Quantity)
+	if leftIsQuantity || rightIsQuantity {
+		if d, ok := left.(Duration); ok {
+			left = durationQuantity(d)
+		}
+		if d, ok := right.(Duration); ok {
+			right = durationQuantity(d)
+		}
+		return quantityArithmetic(left, op, right)
+	}
+
+	// Длительность и число
+	if leftIsDuration {
+		x, ok := toFloat(right)
+		if !ok {
+			return nil, fmt.Errorf("операция %s невозможна между %s и %s", op, left, right)
+		}
+		switch op {
+		case "*":
+			return durationResult(float64(ld) * x)
+		case "/":
+			if x == 0 {
+				return nil, errors.New("деление на ноль")
+			}
+			return durationResult(float64(ld) / x)
+		}
+	} else {
+		x, ok := toFloat(left)
+		if !ok {
+			return nil, fmt.Errorf("операция %s невозможна между %s и %s", op, left, right)
+		}
+		switch {
+		case op == "*":
+			return durationResult(x * float64(rd))
+		case op == "-" && x == 0: // унарный минус
+			return Duration(-rd), nil
+		}
+	}
+	return nil, fmt.Errorf("операция %s не определена для длительностей", op)
+}
+
+// Compare сравнивает две даты
+func (t Time) Compare(other Value) (int, error) {
+	o, ok := other.(Time)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return time.Time(t).Compare(time.Time(o)), nil
+}
+
+// Compare сравнивает длительности, в том числе с величиной времени: 1h == 3600 s
+func (d Duration) Compare(other Value) (int, error) {
+	o, ok := toDuration(other)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return compareFloats(float64(d), float64(o)), nil
+}
+
+// Форматы, которые понимает date(): дата, дата со временем, RFC 3339
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"02.01.2006",
+	"02.01.2006 15:04",
+}
+
+var dateBuiltins = map[string]*Builtin{
+	"now": {MinArgs: 0, MaxArgs: 0, Fn: func(args []Value) (Value, error) {
+		return Time(time.Now()), nil
+	}},
+	"today": {MinArgs: 0, MaxArgs: 0, Fn: func(args []Value) (Value, error) {
+		y, m, d := time.Now().Date()
+		return Time(time.Date(y, m, d, 0, 0, 0, 0, time.Local)), nil
+	}},
+	"date":     {MinArgs: 1, MaxArgs: 2, Fn: builtinDate},
+	"duration": {MinArgs: 1, MaxArgs: 1, Fn: builtinDuration},
+	"tz": {MinArgs: 2, MaxArgs: 2, Fn: func(args []Value) (Value, error) {
+		t, err := timeArg("tz", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := locationArg("tz", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Time(t.In(loc)), nil
+	}},
+	"format": {MinArgs: 2, MaxArgs: 2, Fn: func(args []Value) (Value, error) {
+		t, err := timeArg("format", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		layout, err := stringArg("format", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		text, err := formatStrftime(t, layout)
+		if err != nil {
+			return nil, err
+		}
+		return String(text), nil
+	}},
+	"year":    timePart("year", func(t time.Time) int { return t.Year() }),
+	"month":   timePart("month", func(t time.Time) int { return int(t.Month()) }),
+	"day":     timePart("day", func(t time.Time) int { return t.Day() }),
+	"hour":    timePart("hour", func(t time.Time) int { return t.Hour() }),
+	"minute":  timePart("minute", func(t time.Time) int { return t.Minute() }),
+	"second":  timePart("second", func(t time.Time) int { return t.Second() }),
+	"weekday": timePart("weekday", func(t time.Time) int { return (int(t.Weekday())+6)%7 + 1 }), // понедельник = 1
+	"unix":    timePart("unix", func(t time.Time) int { return int(t.Unix()) }),
+	"days":    durationPart("days", 24*time.Hour),
+	"hours":   durationPart("hours", time.Hour),
+	"minutes": durationPart("minutes", time.Minute),
+	"seconds": durationPart("seconds", time.Second),
+}
+
+// date(текст[, пояс]) — момент времени; без пояса используется местное время
+func builtinDate(args []Value) (Value, error) {
+	text, err := stringArg("date", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if len(args) == 2 {
+		if loc, err = locationArg("date", args, 1); err != nil {
+			return nil, err
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(text), loc); err == nil {
+			return Time(t), nil
+		}
+	}
+	return nil, fmt.Errorf("date: не удалось разобрать дату %q (ожидается, например, 2026-10-16 или 2026-10-16 15:04)", text)
+}
+
+// duration("3d 4h") — длительность из строки
+func builtinDuration(args []Value) (Value, error) {
+	text, err := stringArg("duration", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	p := NewParser(text)
+	if p.currentToken.Type != TokenDuration {
+		return nil, fmt.Errorf("duration: некорректная длительность %q", text)
+	}
+	node, err := p.parseDuration()
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.Type != TokenEOF || p.lexer.Err() != nil {
+		return nil, fmt.Errorf("duration: некорректная длительность %q", text)
+	}
+	return node.Value(nil)
+}
+
+func timeArg(name string, args []Value, i int) (time.Time, error) {
+	t, ok := args[i].(Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s: аргумент %d должен быть датой", name, i+1)
+	}
+	return time.Time(t), nil
+}
+
+func locationArg(name string, args []Value, i int) (*time.Location, error) {
+	zone, err := stringArg(name, args, i)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: неизвестный часовой пояс %q", name, zone)
+	}
+	return loc, nil
+}
+
+func timePart(name string, f func(t time.Time) int) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			t, err := timeArg(name, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return Integer(f(t)), nil
+		},
+	}
+}
+
+// durationPart — длительность в днях, часах, минутах или секундах
+func durationPart(name string, unit time.Duration) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			d, ok := toDuration(args[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: аргумент должен быть длительностью", name)
+			}
+			return Number(float64(d) / float64(unit)), nil
+		},
+	}
+}
+
+// Директивы format() в стиле strftime и соответствующие им макеты пакета time
+var strftimeLayouts = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'Z': "MST",
+	'z': "-0700",
+}
+
+// formatStrftime форматирует дату по шаблону вида "%d.%m.%Y %H:%M"
+func formatStrftime(t time.Time, layout string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			sb.WriteByte(layout[i])
+			continue
+		}
+		if i+1 == len(layout) {
+			return "", errors.New("format: шаблон не может заканчиваться на '%'")
+		}
+		i++
+		switch c := layout[i]; c {
+		case '%':
+			sb.WriteByte('%')
+		case 'j':
+			fmt.Fprintf(&sb, "%03d", t.YearDay())
+		default:
+			goLayout, ok := strftimeLayouts[c]
+			if !ok {
+				return "", fmt.Errorf("format: неизвестная директива %%%c", c)
+			}
+			sb.WriteString(t.Format(goLayout))
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Env — окружение, в котором вычисляется выражение: глобальные переменные,
+// пользовательские функции и локальная область видимости текущего вызова.
+type Env struct {
+	globals   map[string]Value
+	functions map[string]*Function
+	locals    map[string]Value // nil на верхнем уровне
+	depth     int              // глубина вложенности вызовов
+
+	maxIterations int
+	iterations    *int // общий счётчик итераций циклов за одно выполнение
+
+	bigMode   bool // режим произвольной точности: числа — Rational
+	precision int  // число значащих десятичных знаков для неточных операций
+
+	tolerance float64 // точность численных методов: solve, integrate
+}
+
+func NewEnv(globals map[string]Value, functions map[string]*Function) *Env {
+	return &Env{
+		globals:       globals,
+		functions:     functions,
+		maxIterations: DefaultMaxIterations,
+		iterations:    new(int),
+		precision:     DefaultPrecision,
+		tolerance:     DefaultTolerance,
+	}
+}
+
+// Lookup ищет имя сначала в локальной области, затем среди констант и глобальных переменных
+func (e *Env) Lookup(name string) (Value, bool) {
+	if val, ok := e.locals[name]; ok {
+		return val, true
+	}
+	if val, ok := constants[name]; ok {
+		return val, true
+	}
+	val, ok := e.globals[name]
+	return val, ok
+}
+
+// matchVariables возвращает значения глобальных числовых переменных,
+// имена которых начинаются с prefix, в порядке имён
+func (e *Env) matchVariables(prefix string) List {
+	var names []string
+	for name, v := range e.globals {
+		if _, ok := toFloat(v); ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make(List, len(names))
+	for i, name := range names {
+		result[i] = e.globals[name]
+		if r, ok := result[i].(Rational); ok {
+			f, _ := r.Rat.Float64()
+			result[i] = Number(f)
+		}
+	}
+	return result
+}
+
+// Set присваивает значение: внутри функции — локально, иначе — в глобальные переменные.
+// Глобальной переменной можно присвоить только значение, которое сохраняется в файл.
+func (e *Env) Set(name string, val Value) error {
+	if _, ok := constants[name]; ok {
+		return fmt.Errorf("нельзя переопределить константу: %s", name)
+	}
+
+	if e.locals != nil {
+		e.locals[name] = val
+		return nil
+	}
+
+	if !assignable(val) {
+		return fmt.Errorf("значение типа %s нельзя присвоить переменной", val.TypeName())
+	}
+	e.globals[name] = val
+	return nil
+}
+
+// call создаёт область видимости для вызова функции.
+// Тело функции видит только свои параметры и глобальные имена.
+func (e *Env) call(params map[string]Value) *Env {
+	scope := *e
+	scope.locals = params
+	scope.depth++
+	return &scope
+}
+
+// bind создаёт область видимости, в которой имя name связано со значением val
+func (e *Env) bind(name string, val Value) *Env {
+	locals := make(map[string]Value, len(e.locals)+1)
+	for k, v := range e.locals {
+		locals[k] = v
+	}
+	locals[name] = val
+	scope := *e
+	scope.locals = locals
+	return &scope
+}
+
+// tick учитывает очередную итерацию цикла и не даёт зациклиться навсегда
+func (e *Env) tick() error {
+	*e.iterations++
+	if e.maxIterations > 0 && *e.iterations > e.maxIterations {
+		return fmt.Errorf("превышен лимит итераций циклов (%d)", e.maxIterations)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseError — ошибка разбора с указанием места во входной строке
+type ParseError struct {
+	Input    string   // разбираемый текст целиком
+	Pos, End int      // байтовые смещения ошибочного участка [Pos, End)
+	Line     int      // строка начала участка, с 1
+	Column   int      // символ в строке, с 1
+	Expected []string // что допустимо на этом месте: "')'", "выражение"
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Line > 1 {
+		return fmt.Sprintf("строка %d, столбец %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("столбец %d: %s", e.Column, e.Message)
+}
+
+// SourceLine возвращает строку ввода с ошибкой и участок в ней в рунах: [from, to)
+func (e *ParseError) SourceLine() (line string, from, to int) {
+	lineStart := strings.LastIndexByte(e.Input[:e.Pos], '\n') + 1
+	lineEnd := len(e.Input)
+	if i := strings.IndexByte(e.Input[lineStart:], '\n'); i >= 0 {
+		lineEnd = lineStart + i
+	}
+	end := min(max(e.End, e.Pos), lineEnd)
+	line = e.Input[lineStart:lineEnd]
+	from = len([]rune(e.Input[lineStart:e.Pos]))
+	to = from + len([]rune(e.Input[e.Pos:end]))
+	return line, from, to
+}
+
+// errorAt создаёт ошибку разбора для участка токена tok
+func (p *Parser) errorAt(tok Token, expected []string, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Input:    p.input,
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Expected: expected,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// errorSpan создаёт ошибку для участка от начала токена from до начала текущего токена
+func (p *Parser) errorSpan(from Token, format string, args ...interface{}) *ParseError {
+	err := p.errorAt(from, nil, format, args...)
+	if p.currentToken.Pos > from.Pos {
+		err.End = p.currentToken.Pos
+		// пробелы перед текущим токеном в участок не входят
+		for err.End > err.Pos && strings.ContainsRune(" \t", rune(p.input[err.End-1])) {
+			err.End--
+		}
+	}
+	return err
+}
+
+// expected сообщает, что на месте текущего токена ожидалось одно из перечисленного
+func (p *Parser) expected(what ...string) *ParseError {
+	return p.errorAt(p.currentToken, what, "ожидается %s, получено: %s", strings.Join(what, " или "), describeToken(p.currentToken))
+}
+
+// unexpected сообщает о токене, с которого не может начинаться выражение
+func (p *Parser) unexpected() *ParseError {
+	if p.currentToken.Type == TokenEOF {
+		return p.errorAt(p.currentToken, []string{"выражение"}, "неожиданный конец выражения")
+	}
+	return p.errorAt(p.currentToken, []string{"выражение"}, "неожиданный токен: %s", describeToken(p.currentToken))
+}
+
+// trailing сообщает о лишнем вводе после законченного выражения: "2 + 3 )", "x = 5 6"
+func (p *Parser) trailing(expected ...string) *ParseError {
+	return p.errorAt(p.currentToken, expected, "неожиданный ввод после выражения: %s", describeToken(p.currentToken))
+}
+
+// asParseError привязывает ошибку без места к текущему токену. Если разбор
+// дошёл до ошибки лексера, возвращается она, в том числе после успешного разбора
+func (p *Parser) asParseError(err error) error {
+	if lexErr := p.lexer.err; lexErr != nil && p.currentToken.Type == TokenEOF {
+		return p.errorAt(lexErr.Token, nil, "%s", lexErr.Message)
+	}
+	var parseErr *ParseError
+	if err == nil || errors.As(err, &parseErr) {
+		return err
+	}
+	return p.errorAt(p.currentToken, nil, "%s", err.Error())
+}
+
+func describeToken(tok Token) string {
+	switch tok.Type {
+	case TokenEOF:
+		return "конец выражения"
+	case TokenNewline:
+		return "перевод строки"
+	case TokenString:
+		return fmt.Sprintf("%q", tok.Value)
+	default:
+		return tok.Value
+	}
+}
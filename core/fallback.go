@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Fallback определяет, что делать с вводом, который не удалось разобрать как выражение
+type Fallback int
+
+const (
+	FallbackOff  Fallback = iota // всегда показывать ошибку разбора
+	FallbackAuto                 // отправлять ассистенту текст на естественном языке
+)
+
+// statementKeywords — слова языка, которые не являются именами в окружении
+var statementKeywords = map[string]bool{
+	"if": true, "else": true, "while": true, "for": true, "in": true,
+	"break": true, "continue": true, "to": true,
+}
+
+// SetFallback задаёт поведение при ошибке разбора (настройка :fallback)
+func (i *Interpreter) SetFallback(f Fallback) {
+	i.fallback = f
+}
+
+// looksLikeText — эвристика для FallbackAuto: ввод похож на фразу, а не на формулу.
+// Парсер понимает только латинские имена, поэтому кириллица и другие алфавиты
+// означают текст. Латиница — текст, если в нём хотя бы три незнакомых слова
+// и они составляют не меньше двух третей ввода: "open the video", но не "sin x + cos y".
+func (i *Interpreter) looksLikeText(command string) bool {
+	for _, r := range command {
+		if unicode.IsLetter(r) && r > unicode.MaxASCII {
+			return true
+		}
+	}
+
+	env := i.env()
+	fields := strings.Fields(command)
+	words := 0
+	for _, field := range fields {
+		word := strings.TrimRightFunc(field, unicode.IsPunct)
+		if len(word) < 2 || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+			continue
+		}
+		if !i.isKnownName(env, word) {
+			words++
+		}
+	}
+	return words >= 3 && 3*words >= 2*len(fields)
+}
+
+// isKnownName сообщает, что слово — имя переменной, функции, единицы или ключевое слово
+func (i *Interpreter) isKnownName(env *Env, name string) bool {
+	if _, ok := env.Lookup(name); ok {
+		return true
+	}
+	_, builtin := builtins[name]
+	_, form := specialForms[name]
+	_, function := i.functions[name]
+	return builtin || form || function || statementKeywords[name] || isUnitName(name)
+}
+
+// executeFallback решает, что делать с вводом, который парсер не понял:
+// похожее на текст уходит ассистенту, остальное возвращает ошибку разбора
+func (i *Interpreter) executeFallback(command string, parseErr error) (string, error) {
+	if i.fallback != FallbackAuto || !i.looksLikeText(command) {
+		return "", parseErr
+	}
+	result, err := i.classifyAndExecute(command)
+	if err != nil {
+		return "", fmt.Errorf("ассистент: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxCallDepth ограничивает глубину рекурсии пользовательских функций
+const MaxCallDepth = 1000
+
+// Function — пользовательская функция вида f(x, y) = x^2 + y
+type Function struct {
+	Name   string
+	Params []string
+	Body   Node
+	Source string // текст определения, по которому функция восстанавливается при загрузке
+}
+
+func (f *Function) Call(env *Env, args []Node) (Value, error) {
+	if len(args) != len(f.Params) {
+		return nil, fmt.Errorf("функция %s ожидает аргументов: %d, получено %d", f.Name, len(f.Params), len(args))
+	}
+	if env.depth >= MaxCallDepth {
+		return nil, fmt.Errorf("превышена максимальная глубина рекурсии (%d)", MaxCallDepth)
+	}
+
+	// Аргументы вычисляются в окружении вызывающего
+	params := make(map[string]Value, len(args))
+	for i, arg := range args {
+		val, err := arg.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		params[f.Params[i]] = val
+	}
+
+	return f.Body.Value(env.call(params))
+}
+
+type FunctionDefNode struct {
+	Function *Function
+}
+
+func (d *FunctionDefNode) Value(env *Env) (Value, error) {
+	env.functions[d.Function.Name] = d.Function
+	return String(d.Function.Source), nil
+}
+
+// Разбирает определение функции: левая часть уже разобрана как вызов f(x, y)
+func (p *Parser) parseFunctionDefinition(call *CallNode, first Token) (Node, error) {
+	start := first.Pos
+	if _, ok := builtins[call.Name]; ok {
+		return nil, p.errorSpan(first, "нельзя переопределить встроенную функцию: %s", call.Name)
+	}
+	if _, ok := specialForms[call.Name]; ok {
+		return nil, p.errorSpan(first, "нельзя переопределить встроенную функцию: %s", call.Name)
+	}
+
+	params := make([]string, 0, len(call.Args))
+	seen := make(map[string]bool, len(call.Args))
+	for _, arg := range call.Args {
+		v, ok := arg.(*VariableNode)
+		if !ok {
+			return nil, p.errorSpan(first, "параметры функции должны быть именами")
+		}
+		if _, ok := constants[v.Name]; ok {
+			return nil, p.errorSpan(first, "константа %s не может быть параметром", v.Name)
+		}
+		if seen[v.Name] {
+			return nil, p.errorSpan(first, "повторяющийся параметр: %s", v.Name)
+		}
+		seen[v.Name] = true
+		params = append(params, v.Name)
+	}
+
+	p.nextToken() // consume '='
+	body, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(p.input)
+	if p.currentToken.Type != TokenEOF {
+		end = p.currentToken.Pos
+	}
+
+	return &FunctionDefNode{Function: &Function{
+		Name:   call.Name,
+		Params: params,
+		Body:   body,
+		Source: strings.TrimSpace(p.input[start:end]),
+	}}, nil
+}
+
+// ParseFunction восстанавливает функцию по тексту определения
+func ParseFunction(source string) (*Function, error) {
+	node, err := NewParser(source).ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	def, ok := node.(*FunctionDefNode)
+	if !ok {
+		return nil, fmt.Errorf("не является определением функции: %s", source)
+	}
+	return def.Function, nil
+}
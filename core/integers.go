@@ -0,0 +1,252 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// BinaryOp — арифметика int64; с дробными числами работают их типы
+func (n Integer) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	if _, ok := other.(Integer); !ok {
+		return nil, ErrUnsupported
+	}
+	left, right := operands(n, other, reversed)
+	if isBitwise(op) {
+		return bitwiseOp(left, op, right)
+	}
+	return intArithmetic(int64(left.(Integer)), op, int64(right.(Integer)))
+}
+
+func (n Integer) Compare(other Value) (int, error) {
+	m, ok := other.(Integer)
+	switch {
+	case !ok:
+		return 0, ErrUnsupported
+	case n < m:
+		return -1, nil
+	case n > m:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// intArithmetic выполняет операцию над двумя int64.
+// Переполнение — ошибка, как и у сдвигов; деление "/" и отрицательная степень дают float64.
+func intArithmetic(left int64, op string, right int64) (Value, error) {
+	switch op {
+	case "+":
+		sum := left + right
+		if (left > 0 && right > 0 && sum < 0) || (left < 0 && right < 0 && sum >= 0) {
+			return nil, overflowError(left, op, right)
+		}
+		return Integer(sum), nil
+	case "-":
+		diff := left - right
+		if (left >= 0 && right < 0 && diff < 0) || (left < 0 && right > 0 && diff >= 0) {
+			return nil, overflowError(left, op, right)
+		}
+		return Integer(diff), nil
+	case "*":
+		if product, ok := mulInt64(left, right); ok {
+			return Integer(product), nil
+		}
+		return nil, overflowError(left, op, right)
+	case "/":
+		if right == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return Number(float64(left) / float64(right)), nil
+	case "//":
+		if right == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		if left == math.MinInt64 && right == -1 {
+			return nil, overflowError(left, op, right)
+		}
+		q := left / right
+		if (left%right != 0) && ((left < 0) != (right < 0)) {
+			q--
+		}
+		return Integer(q), nil
+	case "%":
+		if right == 0 {
+			return nil, errors.New("деление по модулю на ноль")
+		}
+		if right == -1 {
+			return Integer(0), nil
+		}
+		// Остаток со знаком делителя, как у "//"
+		r := left % right
+		if r != 0 && (r < 0) != (right < 0) {
+			r += right
+		}
+		return Integer(r), nil
+	case "^", "**":
+		if right < 0 {
+			if left == 0 {
+				return nil, errors.New("деление на ноль")
+			}
+			return Number(math.Pow(float64(left), float64(right))), nil
+		}
+		if result, ok := powInt64(left, right); ok {
+			return Integer(result), nil
+		}
+		return nil, overflowError(left, op, right)
+	default:
+		return nil, fmt.Errorf("неизвестный оператор: %s", op)
+	}
+}
+
+// overflowError — результат целочисленной операции не помещается в int64.
+// Приближённо его можно вычислить, записав операнд с точкой (2.0^64), точно — в режиме :mode big.
+func overflowError(left int64, op string, right int64) error {
+	return fmt.Errorf("переполнение int64: %d %s %d (для вычисления с плавающей точкой запишите %d.0, для точного — включите :mode big)", left, op, right, left)
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	hi, lo := bits.Mul64(absUint64(a), absUint64(b))
+	negative := (a < 0) != (b < 0)
+	if hi != 0 {
+		return 0, false
+	}
+	if negative {
+		if lo > 1<<63 {
+			return 0, false
+		}
+		return -int64(lo-1) - 1, true
+	}
+	if lo > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(lo), true
+}
+
+func absUint64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+func powInt64(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			var ok bool
+			if result, ok = mulInt64(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			var ok bool
+			if base, ok = mulInt64(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+func isBitwise(op string) bool {
+	switch op {
+	case "&", "|", "xor", "<<", ">>":
+		return true
+	}
+	return false
+}
+
+// toInt приводит значение к int64: целые числа и дробные без дробной части
+func toInt(v Value) (int64, bool) {
+	switch n := v.(type) {
+	case Integer:
+		return int64(n), true
+	case Number:
+		if f := float64(n); f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case Rational:
+		if !n.Rat.IsInt() || !n.Rat.Num().IsInt64() {
+			return 0, false
+		}
+		return n.Rat.Num().Int64(), true
+	default:
+		return 0, false
+	}
+}
+
+func bitwiseOp(left Value, op string, right Value) (Value, error) {
+	l, ok1 := toInt(left)
+	r, ok2 := toInt(right)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("операция %s возможна только между целыми числами", op)
+	}
+
+	switch op {
+	case "&":
+		return Integer(l & r), nil
+	case "|":
+		return Integer(l | r), nil
+	case "xor":
+		return Integer(l ^ r), nil
+	case "<<":
+		if r < 0 || r > 63 {
+			return nil, fmt.Errorf("недопустимая величина сдвига: %d", r)
+		}
+		result := l << uint(r)
+		if result>>uint(r) != l {
+			return nil, fmt.Errorf("переполнение int64 при сдвиге %d << %d", l, r)
+		}
+		return Integer(result), nil
+	case ">>":
+		if r < 0 || r > 63 {
+			return nil, fmt.Errorf("недопустимая величина сдвига: %d", r)
+		}
+		return Integer(l >> uint(r)), nil
+	default:
+		return nil, fmt.Errorf("неизвестный оператор: %s", op)
+	}
+}
+
+// BitNotNode — побитовое отрицание ~x
+type BitNotNode struct {
+	Expr Node
+}
+
+func (n *BitNotNode) Value(env *Env) (Value, error) {
+	val, err := n.Expr.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := toInt(val)
+	if !ok {
+		return nil, errors.New("операция ~ возможна только для целых чисел")
+	}
+	return Integer(^i), nil
+}
+
+var intBuiltins = map[string]*Builtin{
+	"int": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		f, ok := toFloat(args[0])
+		if !ok {
+			return nil, errors.New("int: аргумент должен быть числом")
+		}
+		if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("int: число %v не помещается в int64", f)
+		}
+		return Integer(f), nil
+	}},
+	"float": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		f, ok := toFloat(args[0])
+		if !ok {
+			return nil, errors.New("float: аргумент должен быть числом")
+		}
+		return Number(f), nil
+	}},
+}
@@ -8,19 +8,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // === Структуры для DeepSeek API ===
 
 type ChatCompletionRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Model          string            `json:"model"`
+	Messages       []Message         `json:"messages"`
+	Temperature    float64           `json:"temperature,omitempty"`
 	ResponseFormat map[string]string `json:"response_format,omitempty"`
 }
 
@@ -37,7 +39,7 @@ var SafeDirs = []string{
 
 var AppPaths = map[string]string{
 	"browser": "firefox", // или "firefox", "chromium", "brave"
-	"player":  "vlc",           // или "mpv", "mpc-hc"
+	"player":  "vlc",     // или "mpv", "mpc-hc"
 }
 
 type LaunchCommand struct {
@@ -67,122 +69,214 @@ type ChatCompletionResponse struct {
 }
 
 type Interpreter struct {
-	variables       map[string]float64
-	stringVariables map[string]string // ← новое поле
-	history         []string
+	variables     map[string]Value
+	functions     map[string]*Function
+	unparsed      map[string]string // определения из файла, которые не удалось разобрать
+	history       []string
+	maxIterations int
+	bigMode       bool
+	precision     int
+	tolerance     float64
+	fallback      Fallback
+	commands      *Commands
 }
 
-func NewInterpreter(vars map[string]float64, strVars map[string]string, history []string) *Interpreter {
-	if vars == nil {
-		vars = make(map[string]float64)
-	}
-	if strVars == nil {
-		strVars = make(map[string]string)
-	}
+func NewInterpreter(vars map[string]Value, history []string) *Interpreter {
 	if history == nil {
 		history = []string{}
 	}
-	return &Interpreter{
-		variables:       vars,
-		stringVariables: strVars,
-		history:         history,
+	i := &Interpreter{
+		variables:     make(map[string]Value, len(vars)),
+		functions:     make(map[string]*Function),
+		unparsed:      make(map[string]string),
+		history:       history,
+		maxIterations: DefaultMaxIterations,
+		precision:     DefaultPrecision,
+		tolerance:     DefaultTolerance,
+		fallback:      FallbackAuto,
+		commands:      NewCommands(),
+	}
+	for name, v := range vars {
+		i.variables[name] = v
+	}
+	for _, cmd := range i.defaultCommands() {
+		if err := i.commands.Register(cmd); err != nil {
+			panic(err)
+		}
 	}
+	return i
 }
 
-func (i *Interpreter) Execute(command string) (interface{}, error) {
-	if strings.TrimSpace(command) == "" {
-		return 0.0, errors.New("пустая команда")
-	}
+func (i *Interpreter) env() *Env {
+	env := NewEnv(i.variables, i.functions)
+	env.maxIterations = i.maxIterations
+	env.bigMode = i.bigMode
+	env.precision = i.precision
+	env.tolerance = i.tolerance
+	return env
+}
 
-	// Проверка на команду curl
-	if strings.HasPrefix(command, "curl ") {
-		return i.executeCurl(command)
-	}
+// SetMaxIterations задаёт лимит итераций циклов за одну команду (0 — без ограничения)
+func (i *Interpreter) SetMaxIterations(n int) {
+	i.maxIterations = n
+}
+
+// SetTolerance задаёт точность численных методов: решения уравнений, интегрирования
+func (i *Interpreter) SetTolerance(tol float64) {
+	i.tolerance = tol
+}
+
+// Precision возвращает число знаков для вывода неточных результатов в режиме big
+func (i *Interpreter) Precision() int {
+	return i.precision
+}
 
-	// Проверка на команду history
-	if command == "history" {
-		return nil, errors.New("history") // специальный случай
+// setMode переключает режим вычислений: ":mode float" или ":mode big [знаков]"
+func (i *Interpreter) setMode(args []string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", errors.New("использование: :mode float | :mode big [знаков]")
 	}
 
-	// Проверка на присваивание с curl
-	if strings.Contains(command, "curl ") {
-		parts := strings.SplitN(command, "=", 2)
-		if len(parts) == 2 {
-			varName := strings.TrimSpace(parts[0])
-			curlPart := strings.TrimSpace(parts[1])
-			if strings.HasPrefix(curlPart, "curl ") {
-				result, err := i.executeCurl(curlPart)
-				if err != nil {
-					return nil, err
-				}
-				i.stringVariables[varName] = result
-				i.history = append(i.history, command)
-				if len(i.history) > 10 {
-					i.history = i.history[1:]
-				}
-				return result, nil
+	switch args[0] {
+	case "float":
+		if len(args) != 1 {
+			return "", errors.New("использование: :mode float")
+		}
+		i.bigMode = false
+		return "режим float64", nil
+	case "big":
+		precision := DefaultPrecision
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return "", fmt.Errorf("некорректная точность: %s", args[1])
 			}
+			precision = n
 		}
+		i.bigMode = true
+		i.precision = precision
+		return fmt.Sprintf("режим произвольной точности, знаков: %d", precision), nil
+	default:
+		return "", fmt.Errorf("неизвестный режим: %s", args[0])
 	}
+}
 
-	// Попытка разбора выражения
-	parser := NewParser(command)
-	node, err := parser.ParseExpression()
-	if err != nil {
-		// Если ошибка — значит, это не выражение
-		// Отправляем в DeepSeek
-		result, err := i.classifyAndExecute(command)
-		if err != nil {
-			return nil, err
-		}
+// setFallback обрабатывает команду ":fallback off|auto"
+func (i *Interpreter) setFallback(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("использование: :fallback off|auto")
+	}
+	switch args[0] {
+	case "off":
+		i.SetFallback(FallbackOff)
+		return "ошибки разбора не передаются ассистенту", nil
+	case "auto":
+		i.SetFallback(FallbackAuto)
+		return "текст на естественном языке передаётся ассистенту", nil
+	default:
+		return "", fmt.Errorf("неизвестный режим: %s", args[0])
+	}
+}
 
-		// Добавляем в историю
-		i.history = append(i.history, command)
-		if len(i.history) > 10 {
-			i.history = i.history[1:]
-		}
+// setMaxIterations обрабатывает команду ":maxiter <число>"
+func (i *Interpreter) setMaxIterations(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("использование: :maxiter <число>")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("некорректный лимит итераций: %s", args[0])
+	}
+	i.SetMaxIterations(n)
+	return fmt.Sprintf("лимит итераций: %d", n), nil
+}
 
-		return result, nil
+// setTolerance обрабатывает команду ":tol <точность>"
+func (i *Interpreter) setTolerance(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("использование: :tol <точность>")
+	}
+	tol, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || tol <= 0 || tol >= 1 {
+		return "", fmt.Errorf("некорректная точность: %s", args[0])
 	}
+	i.SetTolerance(tol)
+	return fmt.Sprintf("точность численных методов: %g", tol), nil
+}
 
-	// Обработка присваивания
-	if assignment, ok := node.(*AssignmentNode); ok {
-		result, err := assignment.Value(i.variables, i.stringVariables)
-		if err != nil {
-			return 0.0, err
-		}
+// Execute выполняет команду или выражение; команда без результата возвращает Null
+func (i *Interpreter) Execute(command string) (Value, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, errors.New("пустая команда")
+	}
 
+	result, record, err := i.dispatch(command)
+	if err != nil {
+		return nil, err
+	}
+
+	// Настройки и служебные команды в историю не попадают
+	if record {
 		i.history = append(i.history, command)
 		if len(i.history) > 10 {
 			i.history = i.history[1:]
 		}
+	}
 
-		return result, nil
+	if result == nil {
+		return Null{}, nil
 	}
+	if r, ok := result.(Rational); ok {
+		// Неточные дроби выводятся с точностью, заданной :mode big
+		r.Digits = i.precision
+		result = r
+	}
+	return result, nil
+}
 
-	// Обычное выражение
-	result, err := node.Value(i.variables, i.stringVariables)
-	if err != nil {
-		return 0.0, err
+// dispatch передаёт ввод команде из реестра, а всё остальное — парсеру;
+// record сообщает, нужно ли записать ввод в историю
+func (i *Interpreter) dispatch(command string) (result Value, record bool, err error) {
+	if cmd, args, ok := i.commands.Find(command); ok {
+		result, err = cmd.Execute(args)
+		return result, cmd.HasResult(), err
 	}
 
-	// Добавляем в историю
-	i.history = append(i.history, command)
-	if len(i.history) > 10 {
-		i.history = i.history[1:]
+	// Результат команды можно сохранить в переменную: page = curl http://...
+	if m := commandAssignment.FindStringSubmatch(command); m != nil {
+		if cmd, args, ok := i.commands.Find(m[2]); ok {
+			if !cmd.HasResult() {
+				return nil, false, fmt.Errorf("команда %s не возвращает значения, его нельзя присвоить переменной", cmd.Name())
+			}
+			result, err := cmd.Execute(args)
+			if err != nil {
+				return nil, false, err
+			}
+			return result, true, i.env().Set(m[1], result)
+		}
 	}
 
-	return result, nil
+	if fields := strings.Fields(command); strings.HasPrefix(fields[0], ":") {
+		return nil, false, fmt.Errorf("неизвестная настройка: %s (список команд: help)", fields[0])
+	}
+	result, err = i.executeExpression(command)
+	return result, true, err
+}
+
+// executeExpression разбирает и вычисляет выражение или программу
+func (i *Interpreter) executeExpression(command string) (Value, error) {
+	node, err := NewParser(command).ParseProgram()
+	if err != nil {
+		return textResult(i.executeFallback(command, err))
+	}
+	return node.Value(i.env())
 }
 
-// Новый метод для выполнения curl
-func (i *Interpreter) executeCurl(command string) (string, error) {
-	// Простой парсер: curl <url>
-	parts := strings.SplitN(strings.TrimSpace(command), " ", 2)
-	if len(parts) < 2 {
+// executeCurl выполняет GET-запрос и возвращает тело ответа
+func (i *Interpreter) executeCurl(url string) (string, error) {
+	if url == "" {
 		return "", errors.New("использование: curl <url>")
 	}
-	url := strings.TrimSpace(parts[1])
 
 	resp, err := http.Get(url)
 	if err != nil {
@@ -198,22 +292,50 @@ func (i *Interpreter) executeCurl(command string) (string, error) {
 	return string(body), nil
 }
 
-func (i *Interpreter) GetVariables() map[string]float64 {
-	result := make(map[string]float64, len(i.variables))
+// Variables возвращает глобальные переменные
+func (i *Interpreter) Variables() map[string]Value {
+	result := make(map[string]Value, len(i.variables))
 	for k, v := range i.variables {
 		result[k] = v
 	}
 	return result
 }
 
-func (i *Interpreter) GetStringVariables() map[string]string {
-	result := make(map[string]string, len(i.stringVariables))
-	for k, v := range i.stringVariables {
-		result[k] = v
+// GetFunctions возвращает тексты определений пользовательских функций.
+// Определения, которые не удалось загрузить, возвращаются как есть, чтобы не потерять их при сохранении.
+func (i *Interpreter) GetFunctions() map[string]string {
+	result := make(map[string]string, len(i.functions)+len(i.unparsed))
+	for name, source := range i.unparsed {
+		result[name] = source
+	}
+	for name, fn := range i.functions {
+		result[name] = fn.Source
 	}
 	return result
 }
 
+// LoadFunctions восстанавливает пользовательские функции по текстам определений.
+// Ошибочные определения пропускаются; ошибки по ним возвращаются вместе.
+func (i *Interpreter) LoadFunctions(defs map[string]string) error {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		fn, err := ParseFunction(defs[name])
+		if err != nil {
+			i.unparsed[name] = defs[name]
+			errs = append(errs, fmt.Errorf("функция %s: %v", name, err))
+			continue
+		}
+		i.functions[fn.Name] = fn
+	}
+	return errors.Join(errs...)
+}
+
 func (i *Interpreter) GetHistory() []string {
 	// Возвращаем копию
 	result := make([]string, len(i.history))
@@ -284,7 +406,6 @@ func (i *Interpreter) sendToDeepSeek(userInput string) (string, error) {
 	return apiResp.Choices[0].Message.Content, nil
 }
 
-
 type ClassificationResult struct {
 	Action *string `json:"action"` // например "сделай краткую сводку"
 	URL    *string `json:"url"`    // например "http://example.com"
@@ -307,7 +428,7 @@ func (i *Interpreter) classifyAndExecute(userInput string) (string, error) {
 				Content: classifyPrompt,
 			},
 		},
-		"temperature": 0.1,
+		"temperature":     0.1,
 		"response_format": map[string]string{"type": "json_object"}, // ✅ Правильное поле
 	}
 
@@ -317,7 +438,7 @@ func (i *Interpreter) classifyAndExecute(userInput string) (string, error) {
 	}
 
 	// === Код аутентификации и отправки запроса (копия из sendToDeepSeek) ===
-	user := "41-2" // или другой
+	user := "41-2"         // или другой
 	password := "U0dMUjFs" // или другой
 
 	auth := user + ":" + password
@@ -392,7 +513,7 @@ func (i *Interpreter) classifyAndExecute(userInput string) (string, error) {
 			// Для простоты: если app == "chrome" или "firefox" — просто открываем сайт
 			// Если app == "curl" или что-то другое — можно добавить обработку
 			switch app {
-				
+
 			case "chrome", "firefox":
 				err := i.launchApp(app, target)
 				if err != nil {
@@ -401,7 +522,7 @@ func (i *Interpreter) classifyAndExecute(userInput string) (string, error) {
 				return fmt.Sprintf("Сайт %s успешно открыт", target), nil
 			case "curl":
 				// Обработка curl + сводка (старая логика)
-				content, err := i.executeCurl("curl " + target)
+				content, err := i.executeCurl(target)
 				if err != nil {
 					return "", err
 				}
@@ -472,7 +593,6 @@ func (i *Interpreter) classifyAndExecute(userInput string) (string, error) {
 	}
 }
 
-
 func (i *Interpreter) findFileInSafeDirs(filename string) (string, error) {
 	// Проверим, не является ли filename абсолютным путём (небезопасно)
 	if filepath.IsAbs(filename) {
@@ -496,7 +616,6 @@ func (i *Interpreter) findFileInSafeDirs(filename string) (string, error) {
 	return "", fmt.Errorf("файл не найден в безопасных директориях: %s", filename)
 }
 
-
 func (i *Interpreter) launchApp(appName, arg string) error {
 	var cmd *exec.Cmd
 
@@ -541,7 +660,6 @@ func (i *Interpreter) launchApp(appName, arg string) error {
 	return cmd.Start()
 }
 
-
 func (i *Interpreter) executeAction(action, target string) (string, error) {
 	action = strings.ToLower(action)
 	target = strings.TrimSpace(target)
@@ -577,4 +695,4 @@ func (i *Interpreter) executeAction(action, target string) (string, error) {
 	}
 
 	return "", fmt.Errorf("неизвестное действие: %s", action)
-}
\ No newline at end of file
+}
@@ -0,0 +1,598 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Списки — значения типа List. Вектор — список чисел,
+// матрица — список строк-векторов одинаковой длины.
+
+// ListNode — литерал списка [a, b, c]; [[1, 2], [3, 4]] задаёт матрицу
+type ListNode struct {
+	Elements []Node
+}
+
+func (l *ListNode) Value(env *Env) (Value, error) {
+	list := make(List, len(l.Elements))
+	for i, el := range l.Elements {
+		val, err := el.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		if list[i], err = listElement(val); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// IndexNode — обращение к элементу списка: v[0], m[1][0]
+type IndexNode struct {
+	Expr  Node
+	Index Node
+}
+
+func (n *IndexNode) Value(env *Env) (Value, error) {
+	val, err := n.Expr.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.Index.Value(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := val.(Map); ok {
+		return mapElement(m, index)
+	}
+	list, ok := val.(List)
+	if !ok {
+		return nil, fmt.Errorf("индексировать можно только список или словарь, получено %s", val)
+	}
+	i, ok := toInt(index)
+	if !ok {
+		return nil, fmt.Errorf("индекс должен быть целым числом, получено %s", index)
+	}
+	if i < 0 || i >= int64(len(list)) {
+		return nil, fmt.Errorf("индекс %d за пределами списка длины %d", i, len(list))
+	}
+	return list[i], nil
+}
+
+// mapElement — значение словаря по ключу: m["name"]
+func mapElement(m Map, index Value) (Value, error) {
+	key, ok := index.(String)
+	if !ok {
+		return nil, fmt.Errorf("ключ словаря должен быть строкой, получено %s", index)
+	}
+	el, ok := m[string(key)]
+	if !ok {
+		return nil, fmt.Errorf("в словаре нет ключа %q", key)
+	}
+	return el, nil
+}
+
+// parseList разбирает литерал списка: [expr, expr, ...]
+func (p *Parser) parseList() (Node, error) {
+	p.nextToken() // consume '['
+	elements := []Node{}
+	if p.currentToken.Type == TokenRBracket {
+		p.nextToken()
+		return &ListNode{Elements: elements}, nil
+	}
+
+	for {
+		el, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, el)
+
+		if p.currentToken.Type == TokenComma {
+			p.nextToken()
+			continue
+		}
+		if p.currentToken.Type != TokenRBracket {
+			return nil, p.expected("','", "']'")
+		}
+		p.nextToken()
+		return &ListNode{Elements: elements}, nil
+	}
+}
+
+// parsePostfix разбирает индексы после первичного выражения: m[0][1]
+func (p *Parser) parsePostfix() (Node, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenLBracket {
+		p.nextToken()
+		index, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if p.currentToken.Type != TokenRBracket {
+			return nil, p.expected("']'")
+		}
+		p.nextToken()
+		node = &IndexNode{Expr: node, Index: index}
+	}
+
+	return node, nil
+}
+
+// listElement проверяет, что значение может быть элементом списка: список
+// сохраняется в файл вместе с элементами, поэтому они должны быть сохраняемыми
+func listElement(v Value) (Value, error) {
+	if !assignable(v) {
+		return nil, fmt.Errorf("элементом списка не может быть значение типа %s", v.TypeName())
+	}
+	return v, nil
+}
+
+// matrixRows возвращает строки матрицы, если список — непустой список строк одинаковой длины
+func matrixRows(list List) ([]List, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	rows := make([]List, len(list))
+	for i, el := range list {
+		row, ok := el.(List)
+		if !ok || len(row) == 0 || (i > 0 && len(row) != len(rows[0])) {
+			return nil, false
+		}
+		rows[i] = row
+	}
+	return rows, true
+}
+
+// BinaryOp выполняет операцию, в которой хотя бы один операнд — список.
+// '*' для матриц — матричное произведение, '^' для квадратной матрицы — степень,
+// остальные операции выполняются поэлементно (число применяется к каждому элементу).
+func (l List) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	left, right := operands(l, other, reversed)
+	return listArithmetic(left, op, right)
+}
+
+func listArithmetic(left Value, op string, right Value) (Value, error) {
+	l, leftIsList := left.(List)
+	r, rightIsList := right.(List)
+
+	if leftIsList && rightIsList && op == "*" {
+		a, aIsMatrix := matrixRows(l)
+		b, bIsMatrix := matrixRows(r)
+		switch {
+		case aIsMatrix && bIsMatrix:
+			return matMul(a, b)
+		case aIsMatrix:
+			// Матрица на вектор: вектор считается столбцом
+			product, err := matMul(a, columnOf(r))
+			if err != nil {
+				return nil, err
+			}
+			return flattenColumn(product), nil
+		case bIsMatrix:
+			// Вектор на матрицу: вектор считается строкой
+			product, err := matMul([]List{l}, b)
+			if err != nil {
+				return nil, err
+			}
+			return product[0], nil
+		}
+	}
+
+	if leftIsList && !rightIsList && (op == "^" || op == "**") {
+		if m, ok := matrixRows(l); ok {
+			n, ok := toInt(right)
+			if !ok {
+				return nil, errors.New("матрицу можно возводить только в целую степень")
+			}
+			return matPow(m, n)
+		}
+	}
+
+	return elementwise(left, op, right)
+}
+
+func elementwise(left Value, op string, right Value) (List, error) {
+	l, leftIsList := left.(List)
+	r, rightIsList := right.(List)
+
+	n := len(l)
+	if !leftIsList {
+		n = len(r)
+	}
+	if leftIsList && rightIsList && len(l) != len(r) {
+		return nil, fmt.Errorf("несовпадение размеров: %d и %d элементов", len(l), len(r))
+	}
+
+	result := make(List, n)
+	for i := range result {
+		x, y := left, right
+		if leftIsList {
+			x = l[i]
+		}
+		if rightIsList {
+			y = r[i]
+		}
+		val, err := binaryOp(x, op, y)
+		if err != nil {
+			return nil, err
+		}
+		if result[i], err = listElement(val); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func matMul(a, b []List) (List, error) {
+	if len(a[0]) != len(b) {
+		return nil, fmt.Errorf("несовпадение размеров: матрицу %dx%d нельзя умножить на %dx%d",
+			len(a), len(a[0]), len(b), len(b[0]))
+	}
+
+	result := make(List, len(a))
+	for i := range a {
+		row := make(List, len(b[0]))
+		for j := range row {
+			var acc Value = Integer(0)
+			for k := range b {
+				product, err := binaryOp(a[i][k], "*", b[k][j])
+				if err != nil {
+					return nil, err
+				}
+				if acc, err = binaryOp(acc, "+", product); err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			if row[j], err = listElement(acc); err != nil {
+				return nil, err
+			}
+		}
+		result[i] = row
+	}
+	return result, nil
+}
+
+func matPow(m []List, n int64) (Value, error) {
+	if len(m) != len(m[0]) {
+		return nil, fmt.Errorf("возводить в степень можно только квадратную матрицу, получена %dx%d", len(m), len(m[0]))
+	}
+	if n < 0 {
+		inverse, err := invert(m)
+		if err != nil {
+			return nil, err
+		}
+		m, _ = matrixRows(inverse)
+		n = -n
+	}
+
+	// Возведение в степень через двоичное разложение показателя
+	result, _ := matrixRows(identity(len(m)))
+	for n > 0 {
+		if n&1 == 1 {
+			product, err := matMul(result, m)
+			if err != nil {
+				return nil, err
+			}
+			result, _ = matrixRows(product)
+		}
+		if n >>= 1; n > 0 {
+			square, err := matMul(m, m)
+			if err != nil {
+				return nil, err
+			}
+			m, _ = matrixRows(square)
+		}
+	}
+
+	rows := make(List, len(result))
+	for i, row := range result {
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+func identity(n int) List {
+	result := make(List, n)
+	for i := range result {
+		row := make(List, n)
+		for j := range row {
+			row[j] = Integer(0)
+		}
+		row[i] = Integer(1)
+		result[i] = row
+	}
+	return result
+}
+
+func columnOf(v List) []List {
+	column := make([]List, len(v))
+	for i, x := range v {
+		column[i] = List{x}
+	}
+	return column
+}
+
+func flattenColumn(m List) List {
+	result := make(List, len(m))
+	for i, row := range m {
+		result[i] = row.(List)[0]
+	}
+	return result
+}
+
+// ratMatrix приводит квадратную числовую матрицу к точным дробям,
+// чтобы det и inv не накапливали ошибку округления
+func ratMatrix(name string, m []List) ([][]*big.Rat, error) {
+	if len(m) != len(m[0]) {
+		return nil, fmt.Errorf("%s: матрица должна быть квадратной, получена %dx%d", name, len(m), len(m[0]))
+	}
+	result := make([][]*big.Rat, len(m))
+	for i, row := range m {
+		result[i] = make([]*big.Rat, len(row))
+		for j, x := range row {
+			r, ok := toRat(x)
+			if !ok || r == nil {
+				return nil, fmt.Errorf("%s: элементы матрицы должны быть конечными числами", name)
+			}
+			result[i][j] = new(big.Rat).Set(r)
+		}
+	}
+	return result, nil
+}
+
+// determinant считает определитель методом Гаусса
+func determinant(m [][]*big.Rat) *big.Rat {
+	n := len(m)
+	det := big.NewRat(1, 1)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col].Sign() == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return new(big.Rat)
+		}
+		if pivot != col {
+			m[pivot], m[col] = m[col], m[pivot]
+			det.Neg(det)
+		}
+		det.Mul(det, m[col][col])
+		for row := col + 1; row < n; row++ {
+			factor := new(big.Rat).Quo(m[row][col], m[col][col])
+			for k := col; k < n; k++ {
+				m[row][k].Sub(m[row][k], new(big.Rat).Mul(factor, m[col][k]))
+			}
+		}
+	}
+	return det
+}
+
+// invert находит обратную матрицу методом Гаусса — Жордана
+func invert(rows []List) (List, error) {
+	m, err := ratMatrix("inv", rows)
+	if err != nil {
+		return nil, err
+	}
+	n := len(m)
+	inv := make([][]*big.Rat, n)
+	for i := range inv {
+		inv[i] = make([]*big.Rat, n)
+		for j := range inv[i] {
+			inv[i][j] = new(big.Rat)
+		}
+		inv[i][i].SetInt64(1)
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col].Sign() == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("inv: матрица вырождена")
+		}
+		m[pivot], m[col] = m[col], m[pivot]
+		inv[pivot], inv[col] = inv[col], inv[pivot]
+
+		scale := new(big.Rat).Inv(m[col][col])
+		for k := 0; k < n; k++ {
+			m[col][k].Mul(m[col][k], scale)
+			inv[col][k].Mul(inv[col][k], scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Rat).Set(m[row][col])
+			for k := 0; k < n; k++ {
+				m[row][k].Sub(m[row][k], new(big.Rat).Mul(factor, m[col][k]))
+				inv[row][k].Sub(inv[row][k], new(big.Rat).Mul(factor, inv[col][k]))
+			}
+		}
+	}
+
+	result := make(List, n)
+	for i, row := range inv {
+		values := make(List, n)
+		for j, r := range row {
+			values[j] = ratToNumber(r)
+		}
+		result[i] = values
+	}
+	return result, nil
+}
+
+// ratToNumber возвращает Integer для целых дробей и Number для остальных
+func ratToNumber(r *big.Rat) Value {
+	if r.IsInt() && r.Num().IsInt64() {
+		return Integer(r.Num().Int64())
+	}
+	f, _ := r.Float64()
+	return Number(f)
+}
+
+var listBuiltins = map[string]*Builtin{
+	"det": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		rows, err := matrixArg("det", args[0])
+		if err != nil {
+			return nil, err
+		}
+		m, err := ratMatrix("det", rows)
+		if err != nil {
+			return nil, err
+		}
+		return ratToNumber(determinant(m)), nil
+	}},
+	"inv": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		rows, err := matrixArg("inv", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return invert(rows)
+	}},
+	"transpose": {MinArgs: 1, MaxArgs: 1, Fn: builtinTranspose},
+	"dot":       {MinArgs: 2, MaxArgs: 2, Fn: builtinDot},
+	"cross":     {MinArgs: 2, MaxArgs: 2, Fn: builtinCross},
+}
+
+func matrixArg(name string, v Value) ([]List, error) {
+	list, ok := v.(List)
+	if ok {
+		if rows, ok := matrixRows(list); ok {
+			return rows, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: аргумент должен быть матрицей", name)
+}
+
+func vectorArg(name string, args []Value, i int) (List, error) {
+	list, ok := args[i].(List)
+	if !ok {
+		return nil, fmt.Errorf("%s: аргумент %d должен быть вектором", name, i+1)
+	}
+	for _, x := range list {
+		if _, ok := toComplex(x); !ok {
+			return nil, fmt.Errorf("%s: аргумент %d должен быть вектором чисел", name, i+1)
+		}
+	}
+	return list, nil
+}
+
+// transpose(m) — транспонирование; вектор превращается в матрицу-столбец
+func builtinTranspose(args []Value) (Value, error) {
+	list, ok := args[0].(List)
+	if !ok {
+		return nil, errors.New("transpose: аргумент должен быть списком")
+	}
+	rows, ok := matrixRows(list)
+	if !ok {
+		column := columnOf(list)
+		result := make(List, len(column))
+		for i, row := range column {
+			result[i] = row
+		}
+		return result, nil
+	}
+
+	result := make(List, len(rows[0]))
+	for j := range result {
+		column := make(List, len(rows))
+		for i, row := range rows {
+			column[i] = row[j]
+		}
+		result[j] = column
+	}
+	return result, nil
+}
+
+func builtinDot(args []Value) (Value, error) {
+	a, err := vectorArg("dot", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := vectorArg("dot", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("dot: несовпадение размеров: %d и %d элементов", len(a), len(b))
+	}
+	products, err := elementwise(a, "*", b)
+	if err != nil {
+		return nil, err
+	}
+	return sumValues("dot", products)
+}
+
+func builtinCross(args []Value) (Value, error) {
+	a, err := vectorArg("cross", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := vectorArg("cross", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) != 3 || len(b) != 3 {
+		return nil, errors.New("cross: векторное произведение определено только для векторов из 3 элементов")
+	}
+
+	result := make(List, 3)
+	for i := range result {
+		j, k := (i+1)%3, (i+2)%3
+		x, err := binaryOp(a[j], "*", b[k])
+		if err != nil {
+			return nil, err
+		}
+		y, err := binaryOp(a[k], "*", b[j])
+		if err != nil {
+			return nil, err
+		}
+		if result[i], err = binaryOp(x, "-", y); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// FormatList выводит список в том же виде, в каком он записывается: [1, 2, "a"]
+func FormatList(list List) string {
+	parts := make([]string, len(list))
+	for i, el := range list {
+		if s, ok := el.(String); ok {
+			parts[i] = strconv.Quote(string(s))
+			continue
+		}
+		parts[i] = el.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// Display выводит вектор в одну строку, а матрицу — по строке на ряд
+func (l List) Display(base int) []string {
+	if _, ok := matrixRows(l); !ok {
+		return []string{l.String()}
+	}
+	lines := make([]string, len(l))
+	for i, row := range l {
+		prefix, suffix := " ", ","
+		if i == 0 {
+			prefix = "["
+		}
+		if i == len(l)-1 {
+			suffix = "]"
+		}
+		lines[i] = prefix + row.String() + suffix
+	}
+	return lines
+}
@@ -0,0 +1,48 @@
+package core
+
+import "testing"
+
+func TestDeterminantAndInverse(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"det([[5]])", "5"},
+		{"det([[1, 2], [3, 4]])", "-2"},
+		{"det([[0, 1], [1, 0]])", "-1"},
+		{"det([[0, 0, 1], [0, 1, 0], [1, 0, 0]])", "-1"},
+		{"det([[1, 2, 3], [4, 5, 6], [7, 8, 10]])", "-3"},
+		{"det([[1, 2], [2, 4]])", "0"},
+		{"inv([[2, 1], [1, 1]])", "[[1, -1], [-1, 2]]"},
+		{"inv([[1, 2], [3, 4]])", "[[-2, 1], [1.5, -0.5]]"},
+		{"inv([[0, 1], [1, 0]])", "[[0, 1], [1, 0]]"},
+		{"inv([[4, 7], [2, 6]])", "[[0.6, -0.7], [-0.2, 0.4]]"},
+		{"inv([[2, 1, 1], [1, 3, 2], [1, 0, 0]])", "[[0, 0, 1], [-2, 1, 3], [3, -1, -5]]"},
+		{"m = [[2, 1, 1], [1, 3, 2], [1, 0, 0]]; m * inv(m)", "[[1, 0, 0], [0, 1, 0], [0, 0, 1]]"},
+	}
+	for _, tt := range tests {
+		v, err := NewInterpreter(nil, nil).Execute(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("%s = %s, ожидалось %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDeterminantAndInverseErrors(t *testing.T) {
+	tests := []struct {
+		input, message string
+	}{
+		{"det([[1, 2, 3], [4, 5, 6]])", "det: матрица должна быть квадратной, получена 2x3"},
+		{"inv([[1, 2], [2, 4]])", "inv: матрица вырождена"},
+		{"inv([[1, 0, 0], [0, 1, 0], [1, 1, 0]])", "inv: матрица вырождена"},
+	}
+	for _, tt := range tests {
+		_, err := NewInterpreter(nil, nil).Execute(tt.input)
+		if err == nil || err.Error() != tt.message {
+			t.Errorf("%s: ошибка %v, ожидалось %q", tt.input, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import "fmt"
+
+// LogicalNode — && и || с сокращённым вычислением: правый операнд
+// вычисляется, только если левого недостаточно для результата
+type LogicalNode struct {
+	Left     Node
+	Operator string
+	Right    Node
+}
+
+func (l *LogicalNode) Value(env *Env) (Value, error) {
+	left, err := l.Left.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	if l.Operator == "&&" && !left.Truthy() {
+		return Bool(false), nil
+	}
+	if l.Operator == "||" && left.Truthy() {
+		return Bool(true), nil
+	}
+
+	right, err := l.Right.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(right.Truthy()), nil
+}
+
+type NotNode struct {
+	Expr Node
+}
+
+func (n *NotNode) Value(env *Env) (Value, error) {
+	val, err := n.Expr.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(!val.Truthy()), nil
+}
+
+type ConditionalNode struct {
+	Cond Node
+	Then Node
+	Else Node
+}
+
+func (c *ConditionalNode) Value(env *Env) (Value, error) {
+	cond, err := c.Cond.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	if cond.Truthy() {
+		return c.Then.Value(env)
+	}
+	return c.Else.Value(env)
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// compareValues: равенство проверяет Value.Equal, порядок — Ordered.Compare
+func compareValues(left Value, op string, right Value) (Value, error) {
+	if op == "==" || op == "!=" {
+		return Bool(left.Equal(right) == (op == "==")), nil
+	}
+	cmp, err := compareOrdered(left, right)
+	switch {
+	case err == ErrUnsupported && left.TypeName() == right.TypeName():
+		return nil, fmt.Errorf("значения типа %s нельзя сравнивать оператором %s", left.TypeName(), op)
+	case err == ErrUnsupported:
+		return nil, fmt.Errorf("нельзя сравнить %s и %s оператором %s", left, right, op)
+	case err != nil:
+		return nil, err
+	}
+	return Bool(compareResult(cmp, op)), nil
+}
+
+// compareOrdered сравнивает значения методом Compare левого операнда, а если
+// тот не умеет работать с правым — методом правого
+func compareOrdered(left, right Value) (int, error) {
+	if o, ok := left.(Ordered); ok {
+		if cmp, err := o.Compare(right); err != ErrUnsupported {
+			return cmp, err
+		}
+	}
+	if o, ok := right.(Ordered); ok {
+		if cmp, err := o.Compare(left); err != ErrUnsupported {
+			return -cmp, err
+		}
+	}
+	return 0, ErrUnsupported
+}
+
+// orderedEqual — равенство упорядоченных значений: 2 == 2.0, 1h == 3600 s
+func orderedEqual(left, right Value) bool {
+	cmp, err := compareOrdered(left, right)
+	return err == nil && cmp == 0
+}
+
+func compareResult(cmp int, op string) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Префиксы целых чисел в других системах счисления
+var numberBases = map[byte]struct {
+	base int
+	name string
+}{
+	'x': {16, "шестнадцатеричном"},
+	'X': {16, "шестнадцатеричном"},
+	'b': {2, "двоичном"},
+	'B': {2, "двоичном"},
+	'o': {8, "восьмеричном"},
+	'O': {8, "восьмеричном"},
+}
+
+// numberToken читает числовой литерал: 42, 3.14, .5, 1e6, 2.5E-3, 1_000_000,
+// 0xFF, 0b1010, 0o17, мнимые 3i, длительности 3d, 250ms. Некорректный литерал — ошибка лексера.
+func (l *Lexer) numberToken(start int) (Token, error) {
+	text, err := l.readNumber()
+	if err != nil {
+		return Token{}, err
+	}
+	// Суффикс i делает литерал мнимым: 3i, 2.5i
+	if l.ch == 'i' && !isLetter(l.peekChar()) && !isDigit(l.peekChar()) {
+		l.readChar()
+		return Token{Type: TokenImaginary, Value: text, Pos: start}, nil
+	}
+	if suffix, ok := l.durationSuffix(); ok {
+		for range suffix {
+			l.readChar()
+		}
+		return Token{Type: TokenDuration, Value: text + suffix, Pos: start}, nil
+	}
+	return Token{Type: TokenNumber, Value: text, Pos: start}, nil
+}
+
+func (l *Lexer) readNumber() (string, error) {
+	start := l.position
+
+	if l.ch == '0' {
+		if prefix, ok := numberBases[l.peekChar()]; ok {
+			l.readChar()
+			l.readChar()
+			n, err := l.readDigits(start, prefix.base)
+			if err != nil {
+				return "", err
+			}
+			if isLetter(l.ch) || isDigit(l.ch) {
+				bad := l.ch
+				return "", l.badNumber(start, fmt.Sprintf("недопустимая цифра '%c' в %s числе %%s", bad, prefix.name))
+			}
+			if n == 0 {
+				return "", l.badNumber(start, "нет цифр после префикса в числе %s")
+			}
+			return l.input[start:l.position], nil
+		}
+	}
+
+	if _, err := l.readDigits(start, 10); err != nil {
+		return "", err
+	}
+
+	if l.ch == '.' && l.peekChar() != '.' {
+		l.readChar()
+		if _, err := l.readDigits(start, 10); err != nil {
+			return "", err
+		}
+		if l.ch == '.' && l.peekChar() != '.' {
+			return "", l.badNumber(start, "лишняя десятичная точка в числе %s")
+		}
+	}
+	if l.position-start == 1 && l.input[start] == '.' {
+		return "", l.badNumber(start, "нет цифр в числе %s")
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if next == '+' || next == '-' {
+			if l.readPosition+1 >= len(l.input) || !isDigit(l.input[l.readPosition+1]) {
+				return "", l.badNumber(start, "нет цифр в показателе степени числа %s")
+			}
+			l.readChar()
+		} else if !isDigit(next) {
+			return "", l.badNumber(start, "нет цифр в показателе степени числа %s")
+		}
+		l.readChar()
+		if _, err := l.readDigits(start, 10); err != nil {
+			return "", err
+		}
+	}
+
+	// Целое с ведущим нулём в других языках бывает восьмеричным, поэтому 0755 неоднозначно
+	text := l.input[start:l.position]
+	if len(text) > 1 && text[0] == '0' && !strings.ContainsAny(text, ".eE") {
+		digits := strings.TrimLeft(text, "0_")
+		switch {
+		case digits == "":
+			return "", fmt.Errorf("лишние нули в числе %s: пишите 0", text)
+		case strings.ContainsAny(digits, "89"):
+			return "", fmt.Errorf("ведущий ноль в числе %s: пишите %s", text, digits)
+		default:
+			return "", fmt.Errorf("ведущий ноль в числе %s: для восьмеричного пишите 0o%s, для десятичного — %s", text, digits, digits)
+		}
+	}
+	return text, nil
+}
+
+// readDigits читает цифры системы счисления base и разделители '_' между ними
+func (l *Lexer) readDigits(start, base int) (int, error) {
+	count := 0
+	for {
+		switch {
+		case isDigitOf(l.ch, base):
+			count++
+			l.readChar()
+		case l.ch == '_':
+			if count == 0 || !isDigitOf(l.peekChar(), base) {
+				return 0, l.badNumber(start, "символ '_' должен стоять между цифрами: %s")
+			}
+			l.readChar()
+		default:
+			return count, nil
+		}
+	}
+}
+
+// badNumber дочитывает остаток ошибочного литерала, чтобы показать его целиком
+func (l *Lexer) badNumber(start int, format string) error {
+	for isLetter(l.ch) || isDigit(l.ch) || (l.ch == '.' && l.peekChar() != '.') {
+		l.readChar()
+	}
+	return fmt.Errorf(format, l.input[start:l.position])
+}
+
+func isDigitOf(ch byte, base int) bool {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch-'0') < base
+	case 'a' <= ch && ch <= 'f':
+		return base == 16
+	case 'A' <= ch && ch <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+// newNumberNode разбирает литерал: целые (без точки и показателя) дают int64,
+// остальные — float64. Text хранит десятичную запись для точного режима.
+func newNumberNode(text string) (*NumberNode, error) {
+	clean := strings.ReplaceAll(text, "_", "")
+
+	if len(clean) > 2 && clean[0] == '0' {
+		if prefix, ok := numberBases[clean[1]]; ok {
+			n, ok := new(big.Int).SetString(clean[2:], prefix.base)
+			if !ok {
+				return nil, fmt.Errorf("некорректное число: %s", text)
+			}
+			return integerNode(n, text)
+		}
+	}
+
+	if !strings.ContainsAny(clean, ".eE") {
+		n, ok := new(big.Int).SetString(clean, 10)
+		if !ok {
+			return nil, fmt.Errorf("некорректное число: %s", text)
+		}
+		return integerNode(n, text)
+	}
+
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) && math.IsInf(f, 0) {
+			return nil, fmt.Errorf("число вне диапазона: %s", text)
+		}
+		if !errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("некорректное число: %s", text)
+		}
+	}
+	return &NumberNode{Val: f, Text: clean}, nil
+}
+
+// integerNode — целый литерал; не помещающийся в int64 становится float64
+func integerNode(n *big.Int, text string) (*NumberNode, error) {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	if math.IsInf(f, 0) {
+		return nil, fmt.Errorf("число вне диапазона: %s", text)
+	}
+	node := &NumberNode{Val: f, Text: n.String()}
+	if n.IsInt64() {
+		node.IsInt = true
+		node.Int = n.Int64()
+	}
+	return node, nil
+}
+
+var baseBuiltins = map[string]*Builtin{
+	"hex": baseFunc("hex", 16),
+	"bin": baseFunc("bin", 2),
+	"oct": baseFunc("oct", 8),
+}
+
+// FormatInt записывает целое в системе счисления base с префиксом 0x, 0b или 0o
+func FormatInt(n int64, base int) string {
+	prefix := map[int]string{16: "0x", 2: "0b", 8: "0o"}[base]
+	if n < 0 {
+		return "-" + prefix + strconv.FormatUint(uint64(-n), base)
+	}
+	return prefix + strconv.FormatInt(n, base)
+}
+
+// baseFunc — функции hex(x), bin(x), oct(x): запись целого числа строкой
+func baseFunc(name string, base int) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			n, ok := toInt(args[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: аргумент должен быть целым числом", name)
+			}
+			return String(FormatInt(n, base)), nil
+		},
+	}
+}
@@ -3,27 +3,64 @@ package core
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"math"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Token types
 const (
 	TokenNumber = iota
+	TokenImaginary
+	TokenDuration
+	TokenString
 	TokenPlus
 	TokenMinus
 	TokenMultiply
 	TokenDivide
+	TokenPower
+	TokenModulo
+	TokenIntDivide
 	TokenLParen
 	TokenRParen
 	TokenIdentifier
 	TokenAssign
+	TokenComma
+	TokenEqual
+	TokenNotEqual
+	TokenLess
+	TokenLessEqual
+	TokenGreater
+	TokenGreaterEqual
+	TokenAnd
+	TokenOr
+	TokenNot
+	TokenQuestion
+	TokenColon
+	TokenLBrace
+	TokenRBrace
+	TokenSemicolon
+	TokenNewline
+	TokenRange
+	TokenBitAnd
+	TokenBitOr
+	TokenTilde
+	TokenShiftLeft
+	TokenShiftRight
+	TokenLBracket
+	TokenRBracket
 	TokenEOF
 )
 
 type Token struct {
 	Type  int
 	Value string
+	Pos   int // смещение начала токена во входной строке, в байтах
+	End   int // смещение байта за концом токена
+	Line  int // номер строки, с 1
+	// Column — номер символа в строке, с 1; в отличие от Pos считает руны, а не байты
+	Column int
 }
 
 type Lexer struct {
@@ -31,6 +68,25 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	err          *lexError // первая ошибка; после неё ввод считается законченным
+}
+
+// lexError — ошибка лексера: недопустимый символ, незакрытая строка, некорректное число
+type lexError struct {
+	Token   // участок ошибочного текста
+	Message string
+}
+
+func (e *lexError) Error() string {
+	return e.Message
+}
+
+// Err возвращает ошибку лексера, если она была
+func (l *Lexer) Err() error {
+	if l.err == nil {
+		return nil
+	}
+	return l.err
 }
 
 func NewLexer(input string) *Lexer {
@@ -57,17 +113,16 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' {
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for unicode.IsDigit(rune(l.ch)) || l.ch == '.' {
+// Комментарий начинается с '#' и продолжается до конца строки
+func (l *Lexer) skipComment() {
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -78,6 +133,41 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// readString читает строку в кавычках с escape-последовательностями.
+// После вызова l.ch указывает на закрывающую кавычку.
+func (l *Lexer) readString() (string, bool) {
+	quote := l.ch
+	var sb strings.Builder
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return "", false
+		case quote:
+			return sb.String(), true
+		case '\\':
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '\\', '"', '\'':
+				sb.WriteByte(l.ch)
+			case 0:
+				return "", false
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(l.ch)
+			}
+		default:
+			sb.WriteByte(l.ch)
+		}
+	}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -86,10 +176,32 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// NextToken читает следующий токен и отмечает его место во входной строке.
+// На ошибке лексер запоминает её (см. Err) и дальше возвращает только TokenEOF
+// в месте ошибки, так что разбор останавливается на ней.
 func (l *Lexer) NextToken() Token {
-	var tok Token
-
+	if l.err != nil {
+		return Token{Type: TokenEOF, Pos: l.err.Pos, End: l.err.Pos, Line: l.err.Line, Column: l.err.Column}
+	}
 	l.skipWhitespace()
+	if l.ch == '#' {
+		l.skipComment()
+	}
+	start := min(l.position, len(l.input))
+	tok, err := l.scanToken(start)
+	tok.Pos, tok.End = start, min(l.position, len(l.input))
+	lineStart := strings.LastIndexByte(l.input[:start], '\n') + 1
+	tok.Line = strings.Count(l.input[:start], "\n") + 1
+	tok.Column = utf8.RuneCountInString(l.input[lineStart:start]) + 1
+	if err != nil {
+		l.err = &lexError{Token: tok, Message: err.Error()}
+		return l.NextToken()
+	}
+	return tok
+}
+
+func (l *Lexer) scanToken(start int) (Token, error) {
+	var tok Token
 
 	switch l.ch {
 	case '+':
@@ -97,60 +209,179 @@ func (l *Lexer) NextToken() Token {
 	case '-':
 		tok = Token{Type: TokenMinus, Value: "-"}
 	case '*':
-		tok = Token{Type: TokenMultiply, Value: "*"}
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = Token{Type: TokenPower, Value: "**"}
+		} else {
+			tok = Token{Type: TokenMultiply, Value: "*"}
+		}
 	case '/':
-		tok = Token{Type: TokenDivide, Value: "/"}
+		if l.peekChar() == '/' {
+			l.readChar()
+			tok = Token{Type: TokenIntDivide, Value: "//"}
+		} else {
+			tok = Token{Type: TokenDivide, Value: "/"}
+		}
+	case '^':
+		tok = Token{Type: TokenPower, Value: "^"}
+	case '%':
+		tok = Token{Type: TokenModulo, Value: "%"}
 	case '(':
 		tok = Token{Type: TokenLParen, Value: "("}
 	case ')':
 		tok = Token{Type: TokenRParen, Value: ")"}
 	case '=':
-		tok = Token{Type: TokenAssign, Value: "="}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenEqual, Value: "=="}
+		} else {
+			tok = Token{Type: TokenAssign, Value: "="}
+		}
+	case '!':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenNotEqual, Value: "!="}
+		} else {
+			tok = Token{Type: TokenNot, Value: "!"}
+		}
+	case '<':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenLessEqual, Value: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = Token{Type: TokenShiftLeft, Value: "<<"}
+		} else {
+			tok = Token{Type: TokenLess, Value: "<"}
+		}
+	case '>':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenGreaterEqual, Value: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: TokenShiftRight, Value: ">>"}
+		} else {
+			tok = Token{Type: TokenGreater, Value: ">"}
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = Token{Type: TokenAnd, Value: "&&"}
+		} else {
+			tok = Token{Type: TokenBitAnd, Value: "&"}
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = Token{Type: TokenOr, Value: "||"}
+		} else {
+			tok = Token{Type: TokenBitOr, Value: "|"}
+		}
+	case '~':
+		tok = Token{Type: TokenTilde, Value: "~"}
+	case '?':
+		tok = Token{Type: TokenQuestion, Value: "?"}
+	case '[':
+		tok = Token{Type: TokenLBracket, Value: "["}
+	case ']':
+		tok = Token{Type: TokenRBracket, Value: "]"}
+	case '{':
+		tok = Token{Type: TokenLBrace, Value: "{"}
+	case '}':
+		tok = Token{Type: TokenRBrace, Value: "}"}
+	case ';':
+		tok = Token{Type: TokenSemicolon, Value: ";"}
+	case '\n':
+		tok = Token{Type: TokenNewline, Value: "\\n"}
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			tok = Token{Type: TokenRange, Value: ".."}
+		} else {
+			return l.numberToken(start)
+		}
+	case ':':
+		tok = Token{Type: TokenColon, Value: ":"}
+	case ',':
+		tok = Token{Type: TokenComma, Value: ","}
+	case '"', '\'':
+		value, ok := l.readString()
+		if !ok {
+			return Token{}, errors.New("незакрытая строка")
+		}
+		tok = Token{Type: TokenString, Value: value}
 	case 0:
 		tok = Token{Type: TokenEOF, Value: ""}
 	default:
 		if isLetter(l.ch) {
 			tok.Value = l.readIdentifier()
 			tok.Type = TokenIdentifier
-			return tok
-		} else if unicode.IsDigit(rune(l.ch)) || l.ch == '.' {
-			tok.Value = l.readNumber()
-			tok.Type = TokenNumber
-			return tok
+			return tok, nil
+		} else if unicode.IsDigit(rune(l.ch)) {
+			return l.numberToken(start)
 		} else {
-			tok = Token{Type: -1, Value: string(l.ch)}
+			return Token{}, l.illegalChar()
 		}
 	}
 
 	l.readChar()
-	return tok
+	return tok, nil
+}
+
+// illegalChar — ошибка на символе, с которого не начинается ни один токен.
+// Символ UTF-8 поглощается целиком, чтобы ошибка указывала на него, а не на его байт.
+func (l *Lexer) illegalChar() error {
+	r, size := utf8.DecodeRuneInString(l.input[l.position:])
+	for i := 0; i < size; i++ {
+		l.readChar()
+	}
+	return fmt.Errorf("недопустимый символ %q", r)
 }
 
 // AST Nodes
 type Node interface {
-	Value(vars map[string]float64, strVars map[string]string) (interface{}, error)
+	Value(env *Env) (Value, error)
 }
 
 type NumberNode struct {
-	Val float64
+	Val   float64
+	Text  string // запись литерала в исходном тексте, для точного режима
+	IsInt bool   // целый литерал, помещающийся в int64
+	Int   int64
+}
+
+func (n *NumberNode) Value(env *Env) (Value, error) {
+	if env.bigMode {
+		r, err := n.exact()
+		if err != nil {
+			return nil, err
+		}
+		return Rational{Rat: r, Digits: env.precision}, nil
+	}
+	if n.IsInt {
+		return Integer(n.Int), nil
+	}
+	return Number(n.Val), nil
+}
+
+type StringNode struct {
+	Val string
 }
 
-func (n *NumberNode) Value(vars map[string]float64, strVars map[string]string) (interface{}, error) {
-	return n.Val, nil
+func (s *StringNode) Value(env *Env) (Value, error) {
+	return String(s.Val), nil
 }
 
 type VariableNode struct {
 	Name string
 }
 
-func (v *VariableNode) Value(vars map[string]float64, strVars map[string]string) (interface{}, error) {
-	if val, ok := vars[v.Name]; ok {
-		return val, nil
-	}
-	if val, ok := strVars[v.Name]; ok {
+func (v *VariableNode) Value(env *Env) (Value, error) {
+	if val, ok := env.Lookup(v.Name); ok {
 		return val, nil
 	}
-	return 0, fmt.Errorf("неизвестная переменная: %s", v.Name)
+	return nil, fmt.Errorf("неизвестная переменная: %s", v.Name)
 }
 
 type BinaryOpNode struct {
@@ -159,70 +390,156 @@ type BinaryOpNode struct {
 	Right    Node
 }
 
-func (b *BinaryOpNode) Value(vars map[string]float64, strVars map[string]string) (interface{}, error) {
-	left, err := b.Left.Value(vars, strVars)
+func (b *BinaryOpNode) Value(env *Env) (Value, error) {
+	left, err := b.Left.Value(env)
 	if err != nil {
 		return nil, err
 	}
-	right, err := b.Right.Value(vars, strVars)
+	right, err := b.Right.Value(env)
 	if err != nil {
 		return nil, err
 	}
+	return binaryOp(left, b.Operator, right)
+}
+
+// binaryOp применяет бинарный оператор к вычисленным операндам. Арифметику реализуют
+// сами типы (см. Arithmetic): оператор предлагается сначала левому операнду, а если
+// тот не умеет работать с правым — правому
+func binaryOp(left Value, op string, right Value) (Value, error) {
+	if isComparison(op) {
+		return compareValues(left, op, right)
+	}
+	if a, ok := left.(Arithmetic); ok {
+		if result, err := a.BinaryOp(op, right, false); err != ErrUnsupported {
+			return result, err
+		}
+	}
+	if a, ok := right.(Arithmetic); ok {
+		if result, err := a.BinaryOp(op, left, true); err != ErrUnsupported {
+			return result, err
+		}
+	}
+	err := fmt.Errorf("оператор %s не применим к значениям типов %s и %s", op, left.TypeName(), right.TypeName())
+	_, leftIsString := left.(String)
+	_, rightIsString := right.(String)
+	if leftIsString || rightIsString {
+		err = fmt.Errorf("%w (строки можно только складывать друг с другом)", err)
+	}
+	return nil, err
+}
+
+// BinaryOp — арифметика float64; целые операнды приводятся к дробным
+func (n Number) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	switch other.(type) {
+	case Number, Integer:
+	default:
+		return nil, ErrUnsupported
+	}
+	left, right := operands(n, other, reversed)
+	if isBitwise(op) {
+		return bitwiseOp(left, op, right)
+	}
+	l, _ := toFloat(left)
+	r, _ := toFloat(right)
+	return floatArithmetic(l, op, r)
+}
 
-	leftNum, ok1 := left.(float64)
-	rightNum, ok2 := right.(float64)
+// Compare сравнивает числа; целые приводятся к float64
+func (n Number) Compare(other Value) (int, error) {
+	switch other.(type) {
+	case Number, Integer:
+		x, _ := toFloat(other)
+		return compareFloats(float64(n), x), nil
+	}
+	return 0, ErrUnsupported
+}
 
-	if !ok1 || !ok2 {
-		return nil, errors.New("арифметические операции возможны только между числами")
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
 	}
+	return 0
+}
 
-	switch b.Operator {
+func floatArithmetic(leftNum float64, op string, rightNum float64) (Value, error) {
+	switch op {
 	case "+":
-		return leftNum + rightNum, nil
+		return Number(leftNum + rightNum), nil
 	case "-":
-		return leftNum - rightNum, nil
+		return Number(leftNum - rightNum), nil
 	case "*":
-		return leftNum * rightNum, nil
+		return Number(leftNum * rightNum), nil
 	case "/":
 		if rightNum == 0 {
 			return nil, errors.New("деление на ноль")
 		}
-		return leftNum / rightNum, nil
+		return Number(leftNum / rightNum), nil
+	case "//":
+		if rightNum == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return Number(math.Floor(leftNum / rightNum)), nil
+	case "%":
+		if rightNum == 0 {
+			return nil, errors.New("деление по модулю на ноль")
+		}
+		return Number(floorMod(leftNum, rightNum)), nil
+	case "^", "**":
+		if leftNum == 0 && rightNum < 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		if leftNum < 0 && rightNum != math.Trunc(rightNum) {
+			// Дробная степень отрицательного числа — комплексное число
+			return complexArithmetic(complex(leftNum, 0), op, complex(rightNum, 0))
+		}
+		result := math.Pow(leftNum, rightNum)
+		if math.IsNaN(result) {
+			return nil, fmt.Errorf("результат возведения %v в степень %v не определён", leftNum, rightNum)
+		}
+		return Number(result), nil
 	default:
-		return nil, fmt.Errorf("неизвестный оператор: %s", b.Operator)
+		return nil, fmt.Errorf("неизвестный оператор: %s", op)
 	}
 }
 
+// floorMod — остаток со знаком делителя, согласованный с "//": a == (a // b)*b + a % b
+func floorMod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
+
 type AssignmentNode struct {
 	Variable string
 	Expr     Node
 }
 
-func (a *AssignmentNode) Value(vars map[string]float64, strVars map[string]string) (interface{}, error) {
-	right, err := a.Expr.Value(vars, strVars)
+func (a *AssignmentNode) Value(env *Env) (Value, error) {
+	right, err := a.Expr.Value(env)
 	if err != nil {
 		return nil, err
 	}
 
-	if val, ok := right.(float64); ok {
-		vars[a.Variable] = val
-		return val, nil
-	} else if val, ok := right.(string); ok {
-		strVars[a.Variable] = val
-		return val, nil
-	} else {
-		return nil, fmt.Errorf("неподдерживаемый тип для присваивания: %T", right)
+	if err := env.Set(a.Variable, right); err != nil {
+		return nil, err
 	}
+	return right, nil
 }
 
 type Parser struct {
+	input        string
 	lexer        *Lexer
 	currentToken Token
 	peekToken    Token
 }
 
 func NewParser(input string) *Parser {
-	p := &Parser{lexer: NewLexer(input)}
+	p := &Parser{input: input, lexer: NewLexer(input)}
 	p.nextToken()
 	p.nextToken()
 	return p
@@ -233,24 +550,33 @@ func (p *Parser) nextToken() {
 	p.peekToken = p.lexer.NextToken()
 }
 
+// ParseExpression разбирает ввод целиком: после выражения допустим только конец ввода
 func (p *Parser) ParseExpression() (Node, error) {
-	return p.parseAssignment()
+	node, err := p.parseAssignment()
+	if err == nil && p.currentToken.Type != TokenEOF {
+		err = p.trailing()
+	}
+	return node, p.asParseError(err)
 }
 
 func (p *Parser) parseAssignment() (Node, error) {
-	node, err := p.parseAdditive()
+	first := p.currentToken
+	node, err := p.parseConversion()
 	if err != nil {
 		return nil, err
 	}
 
 	if p.currentToken.Type == TokenAssign {
+		if call, ok := node.(*CallNode); ok {
+			return p.parseFunctionDefinition(call, first)
+		}
 		varNode, ok := node.(*VariableNode)
 		if !ok {
-			return nil, errors.New("слева от '=' должно быть имя переменной")
+			return nil, p.errorSpan(first, "слева от '=' должно быть имя переменной")
 		}
 		varName := varNode.Name
 		p.nextToken() // consume '='
-		right, err := p.parseAdditive()
+		right, err := p.parseConversion()
 		if err != nil {
 			return nil, err
 		}
@@ -260,6 +586,184 @@ func (p *Parser) parseAssignment() (Node, error) {
 	return node, nil
 }
 
+// cond ? a : b — вычисляется только выбранная ветка
+func (p *Parser) parseTernary() (Node, error) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken.Type != TokenQuestion {
+		return cond, nil
+	}
+	p.nextToken()
+
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.Type != TokenColon {
+		return nil, p.expected("':'")
+	}
+	p.nextToken()
+
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ConditionalNode{Cond: cond, Then: then, Else: otherwise}, nil
+}
+
+func (p *Parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenOr {
+		p.nextToken()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalNode{Left: left, Operator: "||", Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseAnd() (Node, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenAnd {
+		p.nextToken()
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalNode{Left: left, Operator: "&&", Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseEquality() (Node, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenEqual || p.currentToken.Type == TokenNotEqual {
+		op := p.currentToken.Value
+		p.nextToken()
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: op, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseRelational() (Node, error) {
+	left, err := p.parseBitOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenLess || p.currentToken.Type == TokenLessEqual ||
+		p.currentToken.Type == TokenGreater || p.currentToken.Type == TokenGreaterEqual {
+		op := p.currentToken.Value
+		p.nextToken()
+		right, err := p.parseBitOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: op, Right: right}
+	}
+
+	return left, nil
+}
+
+// Побитовые операции связывают сильнее сравнений: x & 1 == 0 — это (x & 1) == 0
+func (p *Parser) parseBitOr() (Node, error) {
+	left, err := p.parseBitXor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenBitOr {
+		p.nextToken()
+		right, err := p.parseBitXor()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: "|", Right: right}
+	}
+
+	return left, nil
+}
+
+// xor записывается словом, так как '^' уже занят под степень
+func (p *Parser) parseBitXor() (Node, error) {
+	left, err := p.parseBitAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenIdentifier && p.currentToken.Value == "xor" {
+		p.nextToken()
+		right, err := p.parseBitAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: "xor", Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseBitAnd() (Node, error) {
+	left, err := p.parseShift()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenBitAnd {
+		p.nextToken()
+		right, err := p.parseShift()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: "&", Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseShift() (Node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenShiftLeft || p.currentToken.Type == TokenShiftRight {
+		op := p.currentToken.Value
+		p.nextToken()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Left: left, Operator: op, Right: right}
+	}
+
+	return left, nil
+}
+
 func (p *Parser) parseAdditive() (Node, error) {
 	left, err := p.parseMultiplicative()
 	if err != nil {
@@ -280,15 +784,16 @@ func (p *Parser) parseAdditive() (Node, error) {
 }
 
 func (p *Parser) parseMultiplicative() (Node, error) {
-	left, err := p.parsePrimary()
+	left, err := p.parseUnary()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.currentToken.Type == TokenMultiply || p.currentToken.Type == TokenDivide {
+	for p.currentToken.Type == TokenMultiply || p.currentToken.Type == TokenDivide ||
+		p.currentToken.Type == TokenModulo || p.currentToken.Type == TokenIntDivide {
 		op := p.currentToken.Value
 		p.nextToken()
-		right, err := p.parsePrimary()
+		right, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
@@ -298,42 +803,161 @@ func (p *Parser) parseMultiplicative() (Node, error) {
 	return left, nil
 }
 
+// Унарный минус связывает слабее степени: -2^2 = -(2^2) = -4
+func (p *Parser) parseUnary() (Node, error) {
+	if p.currentToken.Type == TokenMinus {
+		p.nextToken()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryOpNode{
+			Left:     &NumberNode{Val: 0, IsInt: true},
+			Operator: "-",
+			Right:    node,
+		}, nil
+	}
+	if p.currentToken.Type == TokenTilde {
+		p.nextToken()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &BitNotNode{Expr: node}, nil
+	}
+	if p.currentToken.Type == TokenNot {
+		p.nextToken()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotNode{Expr: node}, nil
+	}
+	return p.parsePower()
+}
+
+// Степень правоассоциативна: 2^3^2 = 2^(3^2)
+func (p *Parser) parsePower() (Node, error) {
+	base, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken.Type == TokenPower {
+		op := p.currentToken.Value
+		p.nextToken()
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryOpNode{Left: base, Operator: op, Right: exponent}, nil
+	}
+
+	return base, nil
+}
+
 func (p *Parser) parsePrimary() (Node, error) {
 	switch p.currentToken.Type {
 	case TokenNumber:
-		val, err := strconv.ParseFloat(p.currentToken.Value, 64)
+		node, err := newNumberNode(p.currentToken.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.nextToken()
+		// Единица измерения после числа: 5 km, 12 kg*m/s^2
+		if p.currentToken.Type == TokenIdentifier && isUnitName(p.currentToken.Value) {
+			unit, err := p.parseUnit(true)
+			if err != nil {
+				return nil, err
+			}
+			return &QuantityNode{Expr: node, Unit: unit}, nil
+		}
+		return node, nil
+	case TokenImaginary:
+		node, err := newNumberNode(p.currentToken.Value)
 		if err != nil {
-			return nil, fmt.Errorf("некорректное число: %s", p.currentToken.Value)
+			return nil, err
 		}
 		p.nextToken()
-		return &NumberNode{Val: val}, nil
+		return &ImaginaryNode{Val: node.Val}, nil
+	case TokenDuration:
+		return p.parseDuration()
+	case TokenString:
+		val := p.currentToken.Value
+		p.nextToken()
+		return &StringNode{Val: val}, nil
 	case TokenIdentifier:
 		name := p.currentToken.Value
+		nameEnd := p.currentToken.End
 		p.nextToken()
+		if p.isPattern(nameEnd) {
+			p.nextToken()
+			return &PatternNode{Prefix: name}, nil
+		}
+		if p.currentToken.Type == TokenLParen {
+			args, err := p.parseCallArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &CallNode{Name: name, Args: args}, nil
+		}
 		return &VariableNode{Name: name}, nil
+	case TokenLBracket:
+		return p.parseList()
 	case TokenLParen:
 		p.nextToken()
-		expr, err := p.parseAdditive()
+		expr, err := p.parseTernary()
 		if err != nil {
 			return nil, err
 		}
 		if p.currentToken.Type != TokenRParen {
-			return nil, errors.New("ожидается ')'")
+			return nil, p.expected("')'")
 		}
 		p.nextToken()
 		return expr, nil
-	case TokenMinus:
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+// Разбирает список аргументов вызова: (expr, expr, ...)
+func (p *Parser) parseCallArgs() ([]Node, error) {
+	p.nextToken() // consume '('
+	args := []Node{}
+	if p.currentToken.Type == TokenRParen {
 		p.nextToken()
-		node, err := p.parsePrimary()
+		return args, nil
+	}
+
+	for {
+		arg, err := p.parseArgument()
 		if err != nil {
 			return nil, err
 		}
-		return &BinaryOpNode{
-			Left:     &NumberNode{Val: 0},
-			Operator: "-",
-			Right:    node,
-		}, nil
-	default:
-		return nil, fmt.Errorf("неожиданный токен: %s", p.currentToken.Value)
+		args = append(args, arg)
+
+		if p.currentToken.Type == TokenComma {
+			p.nextToken()
+			continue
+		}
+		if p.currentToken.Type != TokenRParen {
+			return nil, p.expected("','", "')'")
+		}
+		p.nextToken()
+		return args, nil
+	}
+}
+
+// Аргумент вызова или элемент списка может быть уравнением: solve(x^2 = 2, x)
+func (p *Parser) parseArgument() (Node, error) {
+	left, err := p.parseTernary()
+	if err != nil || p.currentToken.Type != TokenAssign {
+		return left, err
 	}
-}
\ No newline at end of file
+	p.nextToken() // consume '='
+	right, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &EquationNode{Left: left, Right: right}, nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		message       string
+		pos, end, col int
+	}{
+		{"2 + 3 )", "неожиданный ввод после выражения: )", 6, 7, 7},
+		{"x = 5 6", "неожиданный ввод после выражения: 6", 6, 7, 7},
+		{"2 @ 3", "недопустимый символ '@'", 2, 3, 3},
+		{`"abc`, "незакрытая строка", 0, 4, 1},
+		{"[1, @]", "недопустимый символ '@'", 4, 5, 5},
+		{"x + 0755", "ведущий ноль в числе 0755: для восьмеричного пишите 0o755, для десятичного — 755", 4, 8, 5},
+	}
+	parsers := map[string]func(p *Parser) (Node, error){
+		"ParseExpression": (*Parser).ParseExpression,
+		"ParseProgram":    (*Parser).ParseProgram,
+	}
+
+	for _, tt := range tests {
+		for name, parse := range parsers {
+			_, err := parse(NewParser(tt.input))
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("%s(%q): ожидалась ParseError, получено %v", name, tt.input, err)
+				continue
+			}
+			if perr.Message != tt.message {
+				t.Errorf("%s(%q): сообщение %q, ожидалось %q", name, tt.input, perr.Message, tt.message)
+			}
+			if perr.Pos != tt.pos || perr.End != tt.end || perr.Column != tt.col {
+				t.Errorf("%s(%q): Pos=%d End=%d Column=%d, ожидалось Pos=%d End=%d Column=%d",
+					name, tt.input, perr.Pos, perr.End, perr.Column, tt.pos, tt.end, tt.col)
+			}
+			if perr.Line != 1 {
+				t.Errorf("%s(%q): Line=%d, ожидалась 1", name, tt.input, perr.Line)
+			}
+		}
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	_, err := NewParser("x = 1\ny = 2 )").ParseProgram()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("ожидалась ParseError, получено %v", err)
+	}
+	if perr.Line != 2 || perr.Column != 7 || perr.Pos != 12 || perr.End != 13 {
+		t.Errorf("Line=%d Column=%d Pos=%d End=%d, ожидалось Line=2 Column=7 Pos=12 End=13",
+			perr.Line, perr.Column, perr.Pos, perr.End)
+	}
+}
+
+func TestParseValid(t *testing.T) {
+	expressions := []string{
+		"2 + 3",
+		"(2 + 3)",
+		"x = 5",
+		"x = 5 * 6",
+		`"abc"`,
+		"[1, 2]",
+		"[1, [2, 3]][1][0]",
+	}
+	for _, input := range expressions {
+		if _, err := NewParser(input).ParseExpression(); err != nil {
+			t.Errorf("ParseExpression(%q): %v", input, err)
+		}
+	}
+
+	programs := []string{
+		"x = 5; y = 6",
+		"x = 5\ny = x * 2",
+		"x = 5;\n\ny = [1, 2]; x + y[0]",
+		"if x > 1 { y = 2 } else { y = 3 }; y",
+	}
+	for _, input := range append(expressions, programs...) {
+		if _, err := NewParser(input).ParseProgram(); err != nil {
+			t.Errorf("ParseProgram(%q): %v", input, err)
+		}
+	}
+}
+
+func TestExecuteRejectsTrailingInput(t *testing.T) {
+	interp := NewInterpreter(nil, nil)
+	if _, err := interp.Execute("x = 5 6"); err == nil {
+		t.Fatal(`Execute("x = 5 6"): ожидалась ошибка`)
+	}
+	if interp.Variables()["x"] != nil {
+		t.Error(`Execute("x = 5 6"): переменная x не должна быть присвоена`)
+	}
+
+	v, err := interp.Execute("x = 5; x * 2")
+	if err != nil {
+		t.Fatalf(`Execute("x = 5; x * 2"): %v`, err)
+	}
+	if !v.Equal(Integer(10)) {
+		t.Errorf(`Execute("x = 5; x * 2") = %s, ожидалось 10`, v)
+	}
+}
+
+func TestLexerError(t *testing.T) {
+	l := NewLexer("x + @ 3")
+	var types []int
+	for tok := l.NextToken(); tok.Type != TokenEOF; tok = l.NextToken() {
+		types = append(types, tok.Type)
+	}
+	if len(types) != 2 || types[0] != TokenIdentifier || types[1] != TokenPlus {
+		t.Errorf("токены до ошибки: %v, ожидались идентификатор и '+'", types)
+	}
+	if err := l.Err(); err == nil || err.Error() != "недопустимый символ '@'" {
+		t.Errorf("Err() = %v, ожидалась ошибка о символе '@'", err)
+	}
+	if tok := l.NextToken(); tok.Type != TokenEOF || tok.Pos != 4 {
+		t.Errorf("после ошибки: тип %d, Pos=%d, ожидался конец ввода в позиции 4", tok.Type, tok.Pos)
+	}
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlotSamples — число точек, в которых вычисляется каждая функция графика
+const PlotSamples = 400
+
+var plotForms = map[string]specialForm{
+	"plot":   plotForm,
+	"export": exportForm,
+}
+
+// Series — выборка одной функции или ряд данных; Y[i] = NaN там, где функция не определена
+type Series struct {
+	Label string
+	X, Y  []float64
+}
+
+// Plot — результат plot(...) или export(...): одна или несколько выборок на общем отрезке.
+// File задан у export: график нужно сохранить в файл, а не выводить в терминал.
+type Plot struct {
+	Series     []Series
+	XMin, XMax float64
+	File       string
+}
+
+// plot(f(x), x, a, b), plot([f(x), g(x)], x, a, b), plot(данные);
+// без отрезка функции строятся от -10 до 10
+func plotForm(env *Env, args []Node) (Value, error) {
+	return buildPlot("plot", env, args)
+}
+
+// export("файл.svg", ...) — те же аргументы, что у plot, но график сохраняется в SVG или PNG
+func exportForm(env *Env, args []Node) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("функция export ожидает не меньше 2 аргументов, получено %d", len(args))
+	}
+	val, err := args[0].Value(env)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := val.(String)
+	if !ok {
+		return nil, errors.New("export: первый аргумент должен быть именем файла")
+	}
+	file := string(name)
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".svg", ".png":
+	default:
+		return nil, fmt.Errorf("export: поддерживаются только файлы .svg и .png, получено %q", file)
+	}
+
+	plot, err := buildPlot("export", env, args[1:])
+	if err != nil {
+		return nil, err
+	}
+	plot.File = file
+	return plot, nil
+}
+
+// buildPlot разбирает аргументы (серии[, x[, a, b]]). Серия — выражение от x
+// или список: вектор [y1, y2, ...] рисуется по x = 1, 2, ..., матрица n×2 — по парам [x, y].
+func buildPlot(name string, env *Env, args []Node) (Plot, error) {
+	if len(args) != 1 && len(args) != 2 && len(args) != 4 {
+		return Plot{}, fmt.Errorf("%s: ожидаются серии, переменная и отрезок: %s(f(x), x, a, b)", name, name)
+	}
+	x := ""
+	if len(args) > 1 {
+		v, ok := args[1].(*VariableNode)
+		if !ok {
+			return Plot{}, fmt.Errorf("%s: второй аргумент должен быть именем переменной", name)
+		}
+		x = v.Name
+	}
+	a, b := -10.0, 10.0
+	if len(args) == 4 {
+		bounds, err := floatNodes(name, env, args[2:])
+		if err != nil {
+			return Plot{}, err
+		}
+		a, b = bounds[0], bounds[1]
+	}
+	if !(a < b) {
+		return Plot{}, fmt.Errorf("%s: левая граница %g должна быть меньше правой %g", name, a, b)
+	}
+
+	exprs := []Node{args[0]}
+	if list, ok := args[0].(*ListNode); ok && !isDataLiteral(list) {
+		exprs = list.Elements
+	}
+	if len(exprs) == 0 {
+		return Plot{}, fmt.Errorf("%s: нет функций для построения", name)
+	}
+
+	plot := Plot{XMin: math.Inf(1), XMax: math.Inf(-1)}
+	for _, expr := range exprs {
+		var series Series
+		var err error
+		if isData(env, expr, x) {
+			series, err = dataSeries(name, env, expr)
+		} else {
+			series, err = Sample(env, expr, x, a, b, PlotSamples)
+		}
+		if err != nil {
+			return Plot{}, err
+		}
+		plot.Series = append(plot.Series, series)
+		plot.XMin = math.Min(plot.XMin, series.X[0])
+		plot.XMax = math.Max(plot.XMax, series.X[len(series.X)-1])
+	}
+	if plot.XMin == plot.XMax {
+		plot.XMin, plot.XMax = plot.XMin-1, plot.XMax+1
+	}
+	return plot, nil
+}
+
+// isDataLiteral отличает ряд данных [1, 4, 9] от списка функций [sin(x), cos(x)]:
+// в ряде данных только числа или пары чисел
+func isDataLiteral(list *ListNode) bool {
+	for _, el := range list.Elements {
+		switch n := el.(type) {
+		case *NumberNode:
+		case *ListNode:
+			if !isDataLiteral(n) {
+				return false
+			}
+		default:
+			if _, ok := negated(el); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isData сообщает, что серия — список данных, а не функция от x (в том числе постоянная)
+func isData(env *Env, expr Node, x string) bool {
+	if x == "" {
+		return true
+	}
+	if (&differ{x: x, env: env}).dependsOn(expr) {
+		return false
+	}
+	val, err := expr.Value(env)
+	_, ok := val.(List)
+	return err == nil && ok
+}
+
+// dataSeries превращает список в ряд данных; точки сортируются по x
+func dataSeries(name string, env *Env, expr Node) (Series, error) {
+	val, err := expr.Value(env)
+	if err != nil {
+		return Series{}, err
+	}
+	list, ok := val.(List)
+	if !ok || len(list) == 0 {
+		return Series{}, fmt.Errorf("%s: %s — не функция от переменной графика и не список данных", name, FormatNode(expr))
+	}
+
+	series := Series{Label: FormatNode(expr)}
+	if rows, ok := matrixRows(list); ok {
+		if len(rows[0]) != 2 {
+			return Series{}, fmt.Errorf("%s: матрица данных должна состоять из пар [x, y]", name)
+		}
+		points := make([][2]float64, len(rows))
+		for i, row := range rows {
+			px, okX := toFloat(row[0])
+			py, okY := toFloat(row[1])
+			if !okX || !okY {
+				return Series{}, fmt.Errorf("%s: точка %s должна состоять из чисел", name, row)
+			}
+			points[i] = [2]float64{px, py}
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i][0] < points[j][0] })
+		for _, pt := range points {
+			series.X = append(series.X, pt[0])
+			series.Y = append(series.Y, pt[1])
+		}
+		return series, nil
+	}
+
+	for i, el := range list {
+		y, ok := toFloat(el)
+		if !ok {
+			return Series{}, fmt.Errorf("%s: значение %s не является числом", name, el)
+		}
+		series.X = append(series.X, float64(i+1))
+		series.Y = append(series.Y, y)
+	}
+	return series, nil
+}
+
+// Sample вычисляет выражение в n равноотстоящих точках отрезка [a, b].
+// Точки, где значение не является вещественным числом (log(-1), 1/0), пропускаются;
+// ошибка возвращается, только если функция не определена нигде.
+func Sample(env *Env, expr Node, x string, a, b float64, n int) (Series, error) {
+	series := Series{Label: FormatNode(expr), X: make([]float64, n), Y: make([]float64, n)}
+	defined := false
+	var firstErr error
+	for i := 0; i < n; i++ {
+		xi := a + (b-a)*float64(i)/float64(n-1)
+		series.X[i] = xi
+		y, err := floatResult("plot", expr, env.bind(x, Number(xi)), xi)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			y = math.NaN()
+		}
+		series.Y[i] = y
+		defined = defined || !math.IsNaN(y) && !math.IsInf(y, 0)
+	}
+	if !defined {
+		return Series{}, firstErr
+	}
+	return series, nil
+}
+
+// YRange подбирает диапазон по оси y по всем выборкам. Отдельные выбросы у полюсов
+// (tan, 1/x) отбрасываются: если крайние значения на порядок дальше 2-го и 98-го
+// процентилей, диапазоном становятся процентили.
+func (p Plot) YRange() (float64, float64) {
+	var values []float64
+	for _, s := range p.Series {
+		for _, y := range s.Y {
+			if !math.IsNaN(y) && !math.IsInf(y, 0) {
+				values = append(values, y)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return -1, 1
+	}
+	sort.Float64s(values)
+	lo, hi := values[0], values[len(values)-1]
+	pLo := values[len(values)*2/100]
+	pHi := values[len(values)-1-len(values)*2/100]
+	if spread := pHi - pLo; spread > 0 && hi-lo > 10*spread {
+		lo, hi = pLo, pHi
+	}
+	if lo == hi {
+		// Постоянная функция: рисуем её посередине
+		return lo - 1, hi + 1
+	}
+	return lo, hi
+}
+
+// FormatPlot — текстовое описание графика для вывода без терминала
+func FormatPlot(p Plot) string {
+	labels := make([]string, len(p.Series))
+	for i, s := range p.Series {
+		labels[i] = s.Label
+	}
+	return fmt.Sprintf("график %s на [%g, %g]", strings.Join(labels, ", "), p.XMin, p.XMax)
+}
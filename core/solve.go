@@ -0,0 +1,466 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	solveMaxIterations = 100   // итераций метода Ньютона
+	brentMaxIterations = 1000  // к кратному корню, как у (x - 1)^3, метод Брента сходится медленно
+	solveSearchLimit   = 1e6   // насколько далеко от начального приближения искать смену знака
+	pivotEpsilon       = 1e-12 // меньший ведущий элемент считается нулём
+)
+
+var solverForms = map[string]specialForm{
+	"solve": solveForm,
+	"root":  rootForm,
+}
+
+// EquationNode — уравнение left = right в аргументах solve
+type EquationNode struct {
+	Left, Right Node
+}
+
+func (n *EquationNode) Value(env *Env) (Value, error) {
+	return nil, errors.New("уравнение можно использовать только в solve")
+}
+
+// residual приводит уравнение к виду f(x) = 0; выражение без '=' считается равным нулю
+func residual(node Node) Node {
+	switch n := node.(type) {
+	case *EquationNode:
+		return binary(n.Left, "-", n.Right)
+	case *BinaryOpNode:
+		if n.Operator == "==" {
+			return binary(n.Left, "-", n.Right)
+		}
+	}
+	return node
+}
+
+// solve(уравнение, x)        — метод Ньютона от x = 1
+// solve(уравнение, x, x0)    — метод Ньютона от x0
+// solve(уравнение, x, a, b)  — метод Брента на отрезке [a, b]
+// solve([уравнения], [x, y]) — система линейных уравнений
+func solveForm(env *Env, args []Node) (Value, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return nil, fmt.Errorf("функция solve ожидает от 2 до 4 аргументов, получено %d", len(args))
+	}
+	if system, ok := args[0].(*ListNode); ok {
+		if len(args) != 2 {
+			return nil, errors.New("solve: для системы уравнений нужны только уравнения и список неизвестных")
+		}
+		return solveLinearSystem(env, system, args[1])
+	}
+
+	x, ok := args[1].(*VariableNode)
+	if !ok {
+		return nil, errors.New("solve: второй аргумент должен быть именем переменной")
+	}
+	expr := residual(args[0])
+	f := evaluator("solve", env, expr, x.Name)
+
+	bounds, err := floatNodes("solve", env, args[2:])
+	if err != nil {
+		return nil, err
+	}
+	var root float64
+	switch len(bounds) {
+	case 2:
+		root, err = brent("solve", f, bounds[0], bounds[1], env.tolerance)
+	case 1:
+		root, err = findRoot("solve", f, derivative(env, expr, x.Name, f), bounds[0], env.tolerance)
+	default:
+		root, err = findRoot("solve", f, derivative(env, expr, x.Name, f), 1, env.tolerance)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Number(root), nil
+}
+
+// root(f, a, b) — корень функции f на отрезке [a, b], root(f, x0) — корень рядом с x0
+func rootForm(env *Env, args []Node) (Value, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("функция root ожидает от 2 до 3 аргументов, получено %d", len(args))
+	}
+	name, ok := args[0].(*VariableNode)
+	if !ok {
+		return nil, errors.New("root: первый аргумент должен быть именем функции")
+	}
+	if _, ok := builtins[name.Name]; !ok {
+		if _, ok := env.functions[name.Name]; !ok {
+			return nil, fmt.Errorf("неизвестная функция: %s", name.Name)
+		}
+	}
+
+	f := func(x float64) (float64, error) {
+		return floatResult("root", call(name.Name, num(x)), env, x)
+	}
+	bounds, err := floatNodes("root", env, args[1:])
+	if err != nil {
+		return nil, err
+	}
+	var root float64
+	if len(bounds) == 2 {
+		root, err = brent("root", f, bounds[0], bounds[1], env.tolerance)
+	} else {
+		root, err = findRoot("root", f, numericDerivative(f), bounds[0], env.tolerance)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Number(root), nil
+}
+
+// evaluator вычисляет выражение при заданном значении переменной x
+func evaluator(name string, env *Env, expr Node, x string) func(float64) (float64, error) {
+	return func(v float64) (float64, error) {
+		return floatResult(name, expr, env.bind(x, Number(v)), v)
+	}
+}
+
+func floatResult(name string, expr Node, env *Env, x float64) (float64, error) {
+	val, err := expr.Value(env)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := toFloat(val)
+	if !ok || math.IsNaN(f) {
+		return 0, fmt.Errorf("%s: при x = %g значение %s не является вещественным числом", name, x, val)
+	}
+	return f, nil
+}
+
+// derivative строит производную символьно, а если это невозможно — численно
+func derivative(env *Env, expr Node, x string, f func(float64) (float64, error)) func(float64) (float64, error) {
+	d := &differ{x: x, env: env}
+	if node, err := d.derive(expr); err == nil {
+		return evaluator("solve", env, Simplify(node), x)
+	}
+	return numericDerivative(f)
+}
+
+// numericDerivative — центральная разность
+func numericDerivative(f func(float64) (float64, error)) func(float64) (float64, error) {
+	return func(x float64) (float64, error) {
+		h := 1e-6 * math.Max(1, math.Abs(x))
+		right, err := f(x + h)
+		if err != nil {
+			return 0, err
+		}
+		left, err := f(x - h)
+		if err != nil {
+			return 0, err
+		}
+		return (right - left) / (2 * h), nil
+	}
+}
+
+func floatNodes(name string, env *Env, nodes []Node) ([]float64, error) {
+	values := make([]float64, len(nodes))
+	for i, node := range nodes {
+		val, err := node.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := toFloat(val)
+		if !ok {
+			return nil, fmt.Errorf("%s: граница %s должна быть числом", name, val)
+		}
+		values[i] = f
+	}
+	return values, nil
+}
+
+// findRoot пробует метод Ньютона, а если он не сошёлся — ищет смену знака
+// на расширяющихся отрезках вокруг x0 и уточняет корень методом Брента
+func findRoot(name string, f, df func(float64) (float64, error), x0, tol float64) (float64, error) {
+	if x, ok := newton(f, df, x0, tol); ok {
+		return snapRoot(f, x), nil
+	}
+
+	for radius := 1.0; radius <= solveSearchLimit; radius *= 4 {
+		const steps = 64
+		prevX, prevF, havePrev := 0.0, 0.0, false
+		for k := 0; k <= steps; k++ {
+			x := x0 - radius + 2*radius*float64(k)/steps
+			fx, err := f(x)
+			if err != nil {
+				havePrev = false
+				continue
+			}
+			if fx == 0 {
+				return x, nil
+			}
+			if havePrev && (fx > 0) != (prevF > 0) {
+				// Смена знака бывает и в точке разрыва, как у 1/x: тогда brent вернёт ошибку
+				if root, err := brent(name, f, prevX, x, tol); err == nil {
+					return root, nil
+				}
+			}
+			prevX, prevF, havePrev = x, fx, true
+		}
+	}
+	return 0, fmt.Errorf("%s: решение не найдено — метод Ньютона не сошёлся за %d итераций, а смены знака в пределах %g от %g нет",
+		name, solveMaxIterations, solveSearchLimit, x0)
+}
+
+func newton(f, df func(float64) (float64, error), x, tol float64) (float64, bool) {
+	for i := 0; i < solveMaxIterations; i++ {
+		fx, err := f(x)
+		if err != nil {
+			return 0, false
+		}
+		if fx == 0 {
+			return x, true
+		}
+		dfx, err := df(x)
+		if err != nil || dfx == 0 || math.IsNaN(dfx) || math.IsInf(dfx, 0) {
+			return 0, false
+		}
+		step := fx / dfx
+		x -= step
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return 0, false
+		}
+		if math.Abs(step) <= tol*(1+math.Abs(x)) {
+			fx, err := f(x)
+			return x, err == nil && math.Abs(fx) < math.Sqrt(tol)
+		}
+	}
+	return 0, false
+}
+
+// brent — метод Брента: сочетает деление отрезка пополам, секущие и обратную
+// квадратичную интерполяцию; требует разных знаков функции на концах отрезка
+func brent(name string, f func(float64) (float64, error), a, b, tolerance float64) (float64, error) {
+	fa, err := f(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f(b)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case fa == 0:
+		return a, nil
+	case fb == 0:
+		return b, nil
+	case (fa > 0) == (fb > 0):
+		return 0, fmt.Errorf("%s: на концах отрезка [%g, %g] функция должна иметь разные знаки", name, a, b)
+	}
+
+	start, end, limit := a, b, math.Max(math.Abs(fa), math.Abs(fb))
+	c, fc := b, fb
+	var d, e float64
+	for i := 0; i < brentMaxIterations; i++ {
+		if (fb > 0) == (fc > 0) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tol := 2*(math.Nextafter(1, 2)-1)*math.Abs(b) + 0.5*tolerance
+		m := 0.5 * (c - b)
+		if math.Abs(m) <= tol || fb == 0 {
+			// У корня значение меньше, чем на концах; у точки разрыва (tan, 1/x) — больше
+			if math.Abs(fb) > limit {
+				return 0, fmt.Errorf("%s: на отрезке [%g, %g] функция меняет знак в точке разрыва %g, а не в корне", name, start, end, b)
+			}
+			return snapRoot(f, b), nil
+		}
+
+		if math.Abs(e) >= tol && math.Abs(fa) > math.Abs(fb) {
+			// Интерполяция: секущая или обратная квадратичная
+			s := fb / fa
+			var p, q float64
+			if a == c {
+				p = 2 * m * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*m*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < math.Min(3*m*q-math.Abs(tol*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				d, e = m, m
+			}
+		} else {
+			d, e = m, m
+		}
+
+		a, fa = b, fb
+		switch {
+		case math.Abs(d) > tol:
+			b += d
+		case m > 0:
+			b += tol
+		default:
+			b -= tol
+		}
+		if fb, err = f(b); err != nil {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("%s: метод Брента не сошёлся за %d итераций", name, brentMaxIterations)
+}
+
+// snapRoot округляет корень до целого, если это не ухудшает невязку: 2, а не 1.9999999999999998
+func snapRoot(f func(float64) (float64, error), x float64) float64 {
+	r := math.Round(x)
+	if r == x || math.Abs(x-r) > 1e-9 {
+		return x
+	}
+	fx, err := f(x)
+	if err != nil {
+		return x
+	}
+	if fr, err := f(r); err == nil && math.Abs(fr) <= math.Abs(fx) {
+		return r
+	}
+	return x
+}
+
+// solveLinearSystem решает систему линейных уравнений методом Гаусса.
+// Коэффициенты находятся подстановкой: a_ij = f_i(e_j) - f_i(0).
+func solveLinearSystem(env *Env, system *ListNode, unknowns Node) (Value, error) {
+	list, ok := unknowns.(*ListNode)
+	if !ok {
+		return nil, errors.New("solve: для системы уравнений неизвестные задаются списком: [x, y]")
+	}
+	names := make([]string, len(list.Elements))
+	for i, el := range list.Elements {
+		v, ok := el.(*VariableNode)
+		if !ok {
+			return nil, errors.New("solve: неизвестные должны быть именами переменных")
+		}
+		names[i] = v.Name
+	}
+	n := len(names)
+	if len(system.Elements) != n {
+		return nil, fmt.Errorf("solve: уравнений %d, а неизвестных %d", len(system.Elements), n)
+	}
+
+	exprs := make([]Node, n)
+	for i, eq := range system.Elements {
+		exprs[i] = residual(eq)
+	}
+	eval := func(point []float64) ([]float64, error) {
+		scope := env
+		for j, name := range names {
+			scope = scope.bind(name, Number(point[j]))
+		}
+		values := make([]float64, n)
+		for i, expr := range exprs {
+			f, err := floatResult("solve", expr, scope, point[0])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = f
+		}
+		return values, nil
+	}
+
+	origin, err := eval(make([]float64, n))
+	if err != nil {
+		return nil, err
+	}
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n+1)
+		a[i][n] = -origin[i]
+	}
+	for j := 0; j < n; j++ {
+		unit := make([]float64, n)
+		unit[j] = 1
+		values, err := eval(unit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range a {
+			a[i][j] = values[i] - origin[i]
+		}
+	}
+
+	// Проверка линейности: в произвольной точке значения должны совпасть с линейной моделью
+	probe := make([]float64, n)
+	for j := range probe {
+		probe[j] = 1.5 + float64(j)
+	}
+	values, err := eval(probe)
+	if err != nil {
+		return nil, err
+	}
+	for i := range a {
+		expected := origin[i]
+		for j := range probe {
+			expected += a[i][j] * probe[j]
+		}
+		if math.Abs(values[i]-expected) > 1e-9*math.Max(1, math.Abs(expected)) {
+			return nil, fmt.Errorf("solve: уравнение %d не является линейным", i+1)
+		}
+	}
+
+	solution, err := gaussSolve(a)
+	if err != nil {
+		return nil, err
+	}
+	result := make(List, n)
+	for i, x := range solution {
+		if r := math.Round(x); math.Abs(x-r) < 1e-9 {
+			x = r
+		}
+		result[i] = Number(x)
+	}
+	return result, nil
+}
+
+// gaussSolve — метод Гаусса с выбором главного элемента; a — расширенная матрица n×(n+1)
+func gaussSolve(a [][]float64) ([]float64, error) {
+	n := len(a)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < pivotEpsilon {
+			return nil, errors.New("solve: система вырождена — решений нет или их бесконечно много")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		for row := col + 1; row < n; row++ {
+			k := a[row][col] / a[col][col]
+			for j := col; j <= n; j++ {
+				a[row][j] -= k * a[col][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := a[i][n]
+		for j := i + 1; j < n; j++ {
+			sum -= a[i][j] * x[j]
+		}
+		x[i] = sum / a[i][i]
+	}
+	return x, nil
+}
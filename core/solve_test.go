@@ -0,0 +1,101 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       func(float64) float64
+		a, b    float64
+		want    float64
+		wantErr bool
+	}{
+		{"x^2 - 2", func(x float64) float64 { return x*x - 2 }, 0, 2, math.Sqrt2, false},
+		{"cos(x) - x", func(x float64) float64 { return math.Cos(x) - x }, 0, 1, 0.7390851332151607, false},
+		{"x^3 - x - 2", func(x float64) float64 { return x*x*x - x - 2 }, 1, 2, 1.5213797068045676, false},
+		{"x - 3 на конце отрезка", func(x float64) float64 { return x - 3 }, 3, 5, 3, false},
+		{"(x - 1)^3", func(x float64) float64 { return (x - 1) * (x - 1) * (x - 1) }, -3, 4, 1, false},
+		{"один знак на концах", func(x float64) float64 { return x*x + 1 }, -1, 1, 0, true},
+		{"разрыв 1/x", func(x float64) float64 { return 1 / x }, -1, 2, 0, true},
+		{"разрыв tan", math.Tan, 1, 2, 0, true},
+	}
+	for _, tt := range tests {
+		f := func(x float64) (float64, error) { return tt.f(x), nil }
+		got, err := brent("solve", f, tt.a, tt.b, DefaultTolerance)
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("%s: ожидалась ошибка, получено %g", tt.name, got)
+		case !tt.wantErr && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !tt.wantErr && math.Abs(got-tt.want) > 1e-9:
+			t.Errorf("%s: корень %.17g, ожидалось %.17g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		x0   float64
+		want float64
+	}{
+		{"x^2 - 4 от 1", func(x float64) float64 { return x*x - 4 }, 1, 2},
+		{"x^2 - 4 от -1", func(x float64) float64 { return x*x - 4 }, -1, -2},
+		// Метод Ньютона уходит от x0 = 0, где производная равна нулю; корень находит поиск смены знака
+		{"x^3 - 2x + 2 от 0", func(x float64) float64 { return x*x*x - 2*x + 2 }, 0, -1.7692923542386314},
+	}
+	for _, tt := range tests {
+		f := func(x float64) (float64, error) { return tt.f(x), nil }
+		got, err := findRoot("solve", f, numericDerivative(f), tt.x0, DefaultTolerance)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: корень %.17g, ожидалось %.17g", tt.name, got, tt.want)
+		}
+	}
+
+	noRoot := func(x float64) (float64, error) { return x*x + 1, nil }
+	if got, err := findRoot("solve", noRoot, numericDerivative(noRoot), 1, DefaultTolerance); err == nil {
+		t.Errorf("x^2 + 1: ожидалась ошибка, получено %g", got)
+	}
+}
+
+func TestGaussSolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       [][]float64
+		want    []float64
+		wantErr bool
+	}{
+		{"2x2", [][]float64{{1, 1, 3}, {1, -1, 1}}, []float64{2, 1}, false},
+		{"нулевой ведущий элемент", [][]float64{{0, 1, 2}, {1, 0, 3}}, []float64{3, 2}, false},
+		{"3x3", [][]float64{{2, 1, -1, 8}, {-3, -1, 2, -11}, {-2, 1, 2, -3}}, []float64{2, 3, -1}, false},
+		{"вырожденная", [][]float64{{1, 2, 3}, {2, 4, 6}}, nil, true},
+		{"несовместная", [][]float64{{1, 1, 1}, {1, 1, 2}}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := gaussSolve(tt.a)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ожидалась ошибка, получено %v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for i := range tt.want {
+			if math.Abs(got[i]-tt.want[i]) > 1e-12 {
+				t.Errorf("%s: решение %v, ожидалось %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultMaxIterations — сколько итераций циклов допускается за одно выполнение команды
+const DefaultMaxIterations = 1000000
+
+var (
+	errBreak    = errors.New("break вне цикла")
+	errContinue = errors.New("continue вне цикла")
+)
+
+// BlockNode — последовательность операторов; значение блока — значение последнего из них
+type BlockNode struct {
+	Statements []Node
+}
+
+func (b *BlockNode) Value(env *Env) (Value, error) {
+	var result Value = Null{}
+	for _, stmt := range b.Statements {
+		val, err := stmt.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		result = val
+	}
+	return result, nil
+}
+
+type IfNode struct {
+	Cond Node
+	Then Node
+	Else Node // nil, если ветки else нет
+}
+
+func (n *IfNode) Value(env *Env) (Value, error) {
+	cond, err := n.Cond.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	if cond.Truthy() {
+		return n.Then.Value(env)
+	}
+	if n.Else != nil {
+		return n.Else.Value(env)
+	}
+	return Null{}, nil
+}
+
+type WhileNode struct {
+	Cond Node
+	Body Node
+}
+
+func (n *WhileNode) Value(env *Env) (Value, error) {
+	for {
+		cond, err := n.Cond.Value(env)
+		if err != nil {
+			return nil, err
+		}
+		if !cond.Truthy() {
+			return Null{}, nil
+		}
+
+		if err := env.tick(); err != nil {
+			return nil, err
+		}
+		if _, err := n.Body.Value(env); err != nil {
+			if err == errBreak {
+				return Null{}, nil
+			}
+			if err != errContinue {
+				return nil, err
+			}
+		}
+	}
+}
+
+// ForNode — цикл for i in from..to, границы включаются
+type ForNode struct {
+	Variable string
+	From     Node
+	To       Node
+	Body     Node
+}
+
+func (n *ForNode) Value(env *Env) (Value, error) {
+	from, err := n.bound(env, n.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := n.bound(env, n.To)
+	if err != nil {
+		return nil, err
+	}
+
+	step := 1.0
+	if from > to {
+		step = -1
+	}
+	// При целых границах переменная цикла тоже целая
+	integral := from == math.Trunc(from) && to == math.Trunc(to)
+
+	for i := from; (step > 0 && i <= to) || (step < 0 && i >= to); i += step {
+		if err := env.tick(); err != nil {
+			return nil, err
+		}
+		var value Value = Number(i)
+		if integral {
+			value = Integer(i)
+		}
+		if err := env.Set(n.Variable, value); err != nil {
+			return nil, err
+		}
+		if _, err := n.Body.Value(env); err != nil {
+			if err == errBreak {
+				return Null{}, nil
+			}
+			if err != errContinue {
+				return nil, err
+			}
+		}
+	}
+	return Null{}, nil
+}
+
+func (n *ForNode) bound(env *Env, node Node) (float64, error) {
+	val, err := node.Value(env)
+	if err != nil {
+		return 0, err
+	}
+	num, ok := toFloat(val)
+	if !ok {
+		return 0, fmt.Errorf("границы диапазона цикла for должны быть числами")
+	}
+	return num, nil
+}
+
+type BreakNode struct{}
+
+func (b *BreakNode) Value(env *Env) (Value, error) {
+	return nil, errBreak
+}
+
+type ContinueNode struct{}
+
+func (c *ContinueNode) Value(env *Env) (Value, error) {
+	return nil, errContinue
+}
+
+// ParseProgram разбирает последовательность операторов, разделённых ';' или переводом строки.
+// Одиночное выражение возвращается как есть, без обёртки в блок.
+func (p *Parser) ParseProgram() (Node, error) {
+	statements, err := p.parseStatements(TokenEOF)
+	if err = p.asParseError(err); err != nil {
+		return nil, err
+	}
+	if len(statements) == 1 {
+		return statements[0], nil
+	}
+	return &BlockNode{Statements: statements}, nil
+}
+
+// IncompleteInput сообщает, что во вводе остались незакрытые блоки '{'
+// и нужно дочитать следующие строки
+func IncompleteInput(input string) bool {
+	lexer := NewLexer(input)
+	depth := 0
+	for {
+		tok := lexer.NextToken()
+		switch tok.Type {
+		case TokenLBrace:
+			depth++
+		case TokenRBrace:
+			depth--
+		case TokenEOF:
+			return depth > 0
+		}
+	}
+}
+
+func (p *Parser) isSeparator() bool {
+	return p.currentToken.Type == TokenSemicolon || p.currentToken.Type == TokenNewline
+}
+
+// parseStatements читает операторы до токена end (не поглощая его)
+func (p *Parser) parseStatements(end int) ([]Node, error) {
+	statements := []Node{}
+	for {
+		for p.isSeparator() {
+			p.nextToken()
+		}
+		if p.currentToken.Type == end {
+			return statements, nil
+		}
+		if p.currentToken.Type == TokenEOF {
+			return nil, p.expected("'}'")
+		}
+
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+
+		if !p.isSeparator() && p.currentToken.Type != end {
+			return nil, p.trailing("';'", "перевод строки")
+		}
+	}
+}
+
+func (p *Parser) parseStatement() (Node, error) {
+	if p.currentToken.Type == TokenIdentifier {
+		switch p.currentToken.Value {
+		case "if":
+			return p.parseIf()
+		case "while":
+			return p.parseWhile()
+		case "for":
+			return p.parseFor()
+		case "break":
+			p.nextToken()
+			return &BreakNode{}, nil
+		case "continue":
+			p.nextToken()
+			return &ContinueNode{}, nil
+		}
+	}
+	return p.parseAssignment()
+}
+
+func (p *Parser) parseBlock() (Node, error) {
+	if p.currentToken.Type != TokenLBrace {
+		return nil, p.expected("'{'")
+	}
+	p.nextToken()
+
+	statements, err := p.parseStatements(TokenRBrace)
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken() // consume '}'
+	return &BlockNode{Statements: statements}, nil
+}
+
+func (p *Parser) parseIf() (Node, error) {
+	p.nextToken() // consume 'if'
+	cond, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	node := &IfNode{Cond: cond, Then: then}
+
+	// else допускается и на следующей строке после '}'
+	if p.currentToken.Type == TokenNewline && p.peekToken.Type == TokenIdentifier && p.peekToken.Value == "else" {
+		p.nextToken()
+	}
+	if p.currentToken.Type != TokenIdentifier || p.currentToken.Value != "else" {
+		return node, nil
+	}
+	p.nextToken() // consume 'else'
+
+	if p.currentToken.Type == TokenIdentifier && p.currentToken.Value == "if" {
+		node.Else, err = p.parseIf()
+	} else {
+		node.Else, err = p.parseBlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *Parser) parseWhile() (Node, error) {
+	p.nextToken() // consume 'while'
+	cond, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &WhileNode{Cond: cond, Body: body}, nil
+}
+
+func (p *Parser) parseFor() (Node, error) {
+	p.nextToken() // consume 'for'
+	if p.currentToken.Type != TokenIdentifier {
+		return nil, p.expected("имя переменной цикла")
+	}
+	variable := p.currentToken.Value
+	p.nextToken()
+
+	if p.currentToken.Type != TokenIdentifier || p.currentToken.Value != "in" {
+		return nil, p.expected("'in'")
+	}
+	p.nextToken()
+
+	from, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.Type != TokenRange {
+		return nil, p.expected("'..'")
+	}
+	p.nextToken()
+	to, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &ForNode{Variable: variable, From: from, To: to, Body: body}, nil
+}
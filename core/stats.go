@@ -0,0 +1,148 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Статистические функции принимают числа и списки вперемешку:
+// mean(1, 2, 3), mean([1, 2, 3]), sum(cost_*). Сами sum и prod — специальные
+// формы (см. seriesForm): они же считают суммы и произведения рядов
+var statsBuiltins = map[string]*Builtin{
+	"mean": {MinArgs: 1, MaxArgs: -1, Fn: func(args []Value) (Value, error) {
+		values := flattenArgs(args)
+		if len(values) == 0 {
+			return nil, errors.New("mean: нет значений")
+		}
+		total, err := sumValues("mean", values)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(total, "/", Integer(len(values)))
+	}},
+	"median": {MinArgs: 1, MaxArgs: -1, Fn: func(args []Value) (Value, error) {
+		nums, err := statValues("median", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Number(percentile(nums, 50)), nil
+	}},
+	"variance": {MinArgs: 1, MaxArgs: -1, Fn: func(args []Value) (Value, error) {
+		nums, err := statValues("variance", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return Number(variance(nums)), nil
+	}},
+	"stddev": {MinArgs: 1, MaxArgs: -1, Fn: func(args []Value) (Value, error) {
+		nums, err := statValues("stddev", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return Number(math.Sqrt(variance(nums))), nil
+	}},
+	// percentile(значения..., p) — p-й процентиль, p от 0 до 100
+	"percentile": {MinArgs: 2, MaxArgs: -1, Fn: func(args []Value) (Value, error) {
+		p, ok := toFloat(args[len(args)-1])
+		if !ok || p < 0 || p > 100 {
+			return nil, errors.New("percentile: последний аргумент должен быть числом от 0 до 100")
+		}
+		nums, err := statValues("percentile", args[:len(args)-1], 1)
+		if err != nil {
+			return nil, err
+		}
+		return Number(percentile(nums, p)), nil
+	}},
+}
+
+// flattenArgs раскрывает списки среди аргументов: sum([1, 2], 3) = sum(1, 2, 3)
+func flattenArgs(args []Value) []Value {
+	result := []Value{}
+	for _, arg := range args {
+		if list, ok := arg.(List); ok {
+			result = append(result, flattenArgs(list)...)
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// sumValues складывает значения, сохраняя тип: сумма целых — целое, комплексных — комплексное.
+// Пустая сумма равна 0.
+func sumValues(name string, values []Value) (Value, error) {
+	return accumulate(name, values, Integer(0), "+")
+}
+
+// accumulate применяет op ко всем значениям, начиная с total
+func accumulate(name string, values []Value, total Value, op string) (Value, error) {
+	for _, v := range values {
+		if _, ok := toComplex(v); !ok {
+			return nil, fmt.Errorf("%s: значение %s не является числом", name, v)
+		}
+		var err error
+		if total, err = binaryOp(total, op, v); err != nil {
+			return nil, err
+		}
+	}
+	return total, nil
+}
+
+// statValues раскрывает списки и проверяет, что значений не меньше min
+func statValues(name string, args []Value, min int) ([]float64, error) {
+	nums, err := floatArgs(name, flattenArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) < min {
+		return nil, fmt.Errorf("%s: недостаточно значений (нужно не меньше %d, получено %d)", name, min, len(nums))
+	}
+	return nums, nil
+}
+
+// variance — выборочная дисперсия (делитель n - 1)
+func variance(nums []float64) float64 {
+	mean := 0.0
+	for _, x := range nums {
+		mean += x
+	}
+	mean /= float64(len(nums))
+
+	sum := 0.0
+	for _, x := range nums {
+		sum += (x - mean) * (x - mean)
+	}
+	return sum / float64(len(nums)-1)
+}
+
+// percentile — процентиль с линейной интерполяцией между соседними значениями
+func percentile(nums []float64, p float64) float64 {
+	sorted := append([]float64{}, nums...)
+	sort.Float64s(sorted)
+
+	pos := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*frac
+}
+
+// PatternNode — все числовые переменные с общим префиксом: cost_*.
+// Допускается только как аргумент функции: sum(cost_*), mean(t_*, 10).
+type PatternNode struct {
+	Prefix string
+}
+
+func (n *PatternNode) Value(env *Env) (Value, error) {
+	return env.matchVariables(n.Prefix), nil
+}
+
+// isPattern проверяет, что за именем сразу следует '*' и конец аргумента
+func (p *Parser) isPattern(nameEnd int) bool {
+	return p.currentToken.Type == TokenMultiply && p.currentToken.Pos == nameEnd &&
+		(p.peekToken.Type == TokenRParen || p.peekToken.Type == TokenComma)
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var stringBuiltins = map[string]*Builtin{
+	"len": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		if list, ok := args[0].(List); ok {
+			return Integer(len(list)), nil
+		}
+		if m, ok := args[0].(Map); ok {
+			return Integer(len(m)), nil
+		}
+		s, err := stringArg("len", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return Integer(len([]rune(s))), nil
+	}},
+	"upper":  stringFunc("upper", strings.ToUpper),
+	"lower":  stringFunc("lower", strings.ToLower),
+	"trim":   stringFunc("trim", strings.TrimSpace),
+	"substr": {MinArgs: 2, MaxArgs: 3, Fn: builtinSubstr},
+	"replace": {MinArgs: 3, MaxArgs: 3, Fn: func(args []Value) (Value, error) {
+		parts, err := stringArgs("replace", args)
+		if err != nil {
+			return nil, err
+		}
+		return String(strings.ReplaceAll(parts[0], parts[1], parts[2])), nil
+	}},
+	"split": {MinArgs: 2, MaxArgs: 3, Fn: builtinSplit},
+	"contains": {MinArgs: 2, MaxArgs: 2, Fn: func(args []Value) (Value, error) {
+		parts, err := stringArgs("contains", args)
+		if err != nil {
+			return nil, err
+		}
+		return Bool(strings.Contains(parts[0], parts[1])), nil
+	}},
+	"num": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		if n, ok := toFloat(args[0]); ok {
+			return Number(n), nil
+		}
+		s, err := stringArg("num", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("num: не удалось преобразовать в число: %q", s)
+		}
+		return Number(n), nil
+	}},
+	"str": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		return String(args[0].String()), nil
+	}},
+}
+
+// BinaryOp: строки можно только складывать друг с другом
+func (s String) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	o, ok := other.(String)
+	if !ok || op != "+" {
+		return nil, ErrUnsupported
+	}
+	if reversed {
+		return o + s, nil
+	}
+	return s + o, nil
+}
+
+func (s String) Compare(other Value) (int, error) {
+	o, ok := other.(String)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return strings.Compare(string(s), string(o)), nil
+}
+
+func stringArg(name string, args []Value, i int) (string, error) {
+	s, ok := args[i].(String)
+	if !ok {
+		return "", fmt.Errorf("%s: аргумент %d должен быть строкой", name, i+1)
+	}
+	return string(s), nil
+}
+
+func stringArgs(name string, args []Value) ([]string, error) {
+	result := make([]string, len(args))
+	for i := range args {
+		s, err := stringArg(name, args, i)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+func intArg(name string, args []Value, i int) (int, error) {
+	n, ok := toInt(args[i])
+	if !ok {
+		return 0, fmt.Errorf("%s: аргумент %d должен быть целым числом", name, i+1)
+	}
+	return int(n), nil
+}
+
+// stringFunc превращает функцию одного строкового аргумента во встроенную функцию
+func stringFunc(name string, f func(string) string) *Builtin {
+	return &Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args []Value) (Value, error) {
+			s, err := stringArg(name, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return String(f(s)), nil
+		},
+	}
+}
+
+// substr(s, start[, length]) — индексы считаются в символах, начиная с 0
+func builtinSubstr(args []Value) (Value, error) {
+	s, err := stringArg("substr", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+
+	start, err := intArg("substr", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || start > len(runes) {
+		return nil, fmt.Errorf("substr: начало %d за пределами строки длины %d", start, len(runes))
+	}
+
+	end := len(runes)
+	if len(args) == 3 {
+		length, err := intArg("substr", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("substr: отрицательная длина %d", length)
+		}
+		if start+length < end {
+			end = start + length
+		}
+	}
+
+	return String(runes[start:end]), nil
+}
+
+// split(s, sep[, n]) — список частей строки после разбиения по разделителю,
+// с индексом n — только n-я часть (с 0)
+func builtinSplit(args []Value) (Value, error) {
+	s, err := stringArg("split", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg("split", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(s, sep)
+	if len(args) == 2 {
+		list := make(List, len(parts))
+		for i, part := range parts {
+			list[i] = String(part)
+		}
+		return list, nil
+	}
+
+	n, err := intArg("split", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= len(parts) {
+		return nil, fmt.Errorf("split: индекс %d за пределами (частей: %d)", n, len(parts))
+	}
+	return String(parts[n]), nil
+}
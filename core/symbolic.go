@@ -0,0 +1,785 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var symbolicForms = map[string]specialForm{
+	"diff": diffForm,
+}
+
+// diff(выражение, x) — производная в виде текста, diff(выражение, x, a) — её значение в точке a.
+// Вложенный diff дифференцируется как выражение: diff(diff(x^3, x), x) = 6*x.
+func diffForm(env *Env, args []Node) (Value, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("функция diff ожидает от 2 до 3 аргументов, получено %d", len(args))
+	}
+	x, ok := args[1].(*VariableNode)
+	if !ok {
+		return nil, errors.New("diff: второй аргумент должен быть именем переменной")
+	}
+
+	d := &differ{x: x.Name, env: env}
+	derivative, err := d.derive(args[0])
+	if err != nil {
+		return nil, err
+	}
+	derivative = Simplify(derivative)
+
+	if len(args) == 2 {
+		return String(FormatNode(derivative)), nil
+	}
+	at, err := args[2].Value(env)
+	if err != nil {
+		return nil, err
+	}
+	return derivative.Value(env.bind(x.Name, at))
+}
+
+// Конструкторы узлов для построения производных
+func num(v float64) *NumberNode {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		return &NumberNode{Val: v, Text: strconv.FormatInt(int64(v), 10), IsInt: true, Int: int64(v)}
+	}
+	return &NumberNode{Val: v, Text: strconv.FormatFloat(v, 'g', -1, 64)}
+}
+
+func binary(left Node, op string, right Node) Node {
+	return &BinaryOpNode{Left: left, Operator: op, Right: right}
+}
+
+func neg(n Node) Node {
+	return binary(num(0), "-", n)
+}
+
+func call(name string, args ...Node) Node {
+	return &CallNode{Name: name, Args: args}
+}
+
+func numberValue(n Node) (float64, bool) {
+	if number, ok := n.(*NumberNode); ok {
+		return number.Val, true
+	}
+	return 0, false
+}
+
+// negated распознаёт унарный минус, который парсер записывает как 0 - x
+func negated(n Node) (Node, bool) {
+	b, ok := n.(*BinaryOpNode)
+	if !ok || b.Operator != "-" {
+		return nil, false
+	}
+	if zero, ok := numberValue(b.Left); ok && zero == 0 {
+		return b.Right, true
+	}
+	return nil, false
+}
+
+// Производные встроенных функций: f'(u) без множителя u'
+var derivativeRules = map[string]func(u Node) Node{
+	"sin":   func(u Node) Node { return call("cos", u) },
+	"cos":   func(u Node) Node { return neg(call("sin", u)) },
+	"tan":   func(u Node) Node { return binary(num(1), "/", binary(call("cos", u), "^", num(2))) },
+	"exp":   func(u Node) Node { return call("exp", u) },
+	"log":   func(u Node) Node { return binary(num(1), "/", u) },
+	"log10": func(u Node) Node { return binary(num(1), "/", binary(u, "*", call("log", num(10)))) },
+	"sqrt":  func(u Node) Node { return binary(num(1), "/", binary(num(2), "*", call("sqrt", u))) },
+	"abs":   func(u Node) Node { return binary(u, "/", call("abs", u)) },
+}
+
+// differ строит производную по переменной x; пользовательские функции подставляются
+type differ struct {
+	x     string
+	env   *Env
+	depth int
+}
+
+func (d *differ) derive(node Node) (Node, error) {
+	switch n := node.(type) {
+	case *NumberNode:
+		return num(0), nil
+	case *VariableNode:
+		if n.Name == d.x {
+			return num(1), nil
+		}
+		return num(0), nil
+	case *BinaryOpNode:
+		return d.deriveBinary(n)
+	case *CallNode:
+		return d.deriveCall(n)
+	default:
+		return nil, fmt.Errorf("diff: выражение нельзя продифференцировать: %s", FormatNode(node))
+	}
+}
+
+func (d *differ) deriveBinary(n *BinaryOpNode) (Node, error) {
+	a, b := n.Left, n.Right
+	da, err := d.derive(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := d.derive(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Operator {
+	case "+", "-":
+		return binary(da, n.Operator, db), nil
+	case "*":
+		return binary(binary(da, "*", b), "+", binary(a, "*", db)), nil
+	case "/":
+		return binary(binary(binary(da, "*", b), "-", binary(a, "*", db)), "/", binary(b, "^", num(2))), nil
+	case "^", "**":
+		switch {
+		case !d.dependsOn(b):
+			// (u^n)' = n*u^(n-1)*u'
+			return binary(binary(b, "*", binary(a, "^", binary(b, "-", num(1)))), "*", da), nil
+		case !d.dependsOn(a):
+			// (a^v)' = a^v*ln(a)*v'
+			return binary(binary(binary(a, "^", b), "*", call("log", a)), "*", db), nil
+		default:
+			// (u^v)' = u^v*(v'*ln(u) + v*u'/u)
+			return binary(binary(a, "^", b), "*",
+				binary(binary(db, "*", call("log", a)), "+", binary(binary(b, "*", da), "/", a))), nil
+		}
+	default:
+		return nil, fmt.Errorf("diff: оператор %s нельзя продифференцировать", n.Operator)
+	}
+}
+
+func (d *differ) deriveCall(n *CallNode) (Node, error) {
+	// Вложенная производная: diff(diff(x^3, x), x)
+	if n.Name == "diff" && len(n.Args) == 2 {
+		x, ok := n.Args[1].(*VariableNode)
+		if !ok {
+			return nil, errors.New("diff: второй аргумент должен быть именем переменной")
+		}
+		if d.depth >= MaxCallDepth {
+			return nil, fmt.Errorf("превышена максимальная глубина рекурсии (%d)", MaxCallDepth)
+		}
+		inner := &differ{x: x.Name, env: d.env, depth: d.depth + 1}
+		du, err := inner.derive(n.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return d.derive(Simplify(du))
+	}
+	if rule, ok := derivativeRules[n.Name]; ok && len(n.Args) == 1 {
+		du, err := d.derive(n.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return binary(rule(n.Args[0]), "*", du), nil
+	}
+
+	fn, ok := d.env.functions[n.Name]
+	if !ok || builtins[n.Name] != nil {
+		return nil, fmt.Errorf("diff: неизвестна производная функции %s", n.Name)
+	}
+	if len(n.Args) != len(fn.Params) {
+		return nil, fmt.Errorf("функция %s ожидает аргументов: %d, получено %d", fn.Name, len(fn.Params), len(n.Args))
+	}
+	if d.depth >= MaxCallDepth {
+		return nil, fmt.Errorf("превышена максимальная глубина рекурсии (%d)", MaxCallDepth)
+	}
+
+	// Тело пользовательской функции подставляется вместо вызова
+	params := make(map[string]Node, len(fn.Params))
+	for i, name := range fn.Params {
+		params[name] = n.Args[i]
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+	return d.derive(substitute(fn.Body, params))
+}
+
+// dependsOn сообщает, входит ли переменная дифференцирования в выражение
+func (d *differ) dependsOn(node Node) bool {
+	switch n := node.(type) {
+	case *VariableNode:
+		return n.Name == d.x
+	case *BinaryOpNode:
+		return d.dependsOn(n.Left) || d.dependsOn(n.Right)
+	case *CallNode:
+		for _, arg := range n.Args {
+			if d.dependsOn(arg) {
+				return true
+			}
+		}
+		if fn, ok := d.env.functions[n.Name]; ok && builtins[n.Name] == nil {
+			return d.depth < MaxCallDepth && d.dependsOn(fn.Body)
+		}
+		return false
+	case *NumberNode:
+		return false
+	default:
+		return true
+	}
+}
+
+// substitute возвращает копию выражения, в которой переменные заменены выражениями
+func substitute(node Node, vars map[string]Node) Node {
+	switch n := node.(type) {
+	case *VariableNode:
+		if replacement, ok := vars[n.Name]; ok {
+			return replacement
+		}
+		return n
+	case *BinaryOpNode:
+		return binary(substitute(n.Left, vars), n.Operator, substitute(n.Right, vars))
+	case *CallNode:
+		args := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = substitute(arg, vars)
+		}
+		return &CallNode{Name: n.Name, Args: args}
+	default:
+		return node
+	}
+}
+
+// Simplify упрощает выражение: сворачивает константы точными дробями, убирает x*1, x+0, x^1,
+// приводит подобные слагаемые и множители и сокращает дроби: x + 2*x = 3*x, x*y/(x*y)^2 = 1/(x*y)
+func Simplify(node Node) Node {
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		return simplifyBinary(Simplify(n.Left), n.Operator, Simplify(n.Right))
+	case *CallNode:
+		args := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = Simplify(arg)
+		}
+		return simplifyCall(n.Name, args)
+	default:
+		return node
+	}
+}
+
+// simplifyCall вычисляет очевидные значения: log(e) = 1, log(1) = 0, exp(0) = 1
+func simplifyCall(name string, args []Node) Node {
+	if len(args) == 1 {
+		v, isNum := numberValue(args[0])
+		switch {
+		case name == "log" && isNum && v == 1, name == "sin" && isNum && v == 0:
+			return num(0)
+		case name == "exp" && isNum && v == 0, name == "cos" && isNum && v == 0:
+			return num(1)
+		}
+		if variable, ok := args[0].(*VariableNode); ok && name == "log" && variable.Name == "e" {
+			return num(1)
+		}
+	}
+	return &CallNode{Name: name, Args: args}
+}
+
+func simplifyBinary(l Node, op string, r Node) Node {
+	lv, lNum := constValue(l)
+	rv, rNum := constValue(r)
+	if lNum && rNum {
+		if v, ok := foldConstants(lv, op, rv); ok {
+			return ratNode(v)
+		}
+	}
+
+	switch op {
+	case "+", "-":
+		return simplifySum(binary(l, op, r))
+	case "*":
+		return simplifyProduct(binary(l, op, r))
+	case "/":
+		switch {
+		case rNum && ratIs(rv, 1):
+			return l
+		case lNum && lv.Sign() == 0:
+			return num(0)
+		}
+		return simplifyProduct(binary(l, op, r))
+	case "^", "**":
+		switch {
+		case rNum && rv.Sign() == 0:
+			return num(1)
+		case rNum && ratIs(rv, 1):
+			return l
+		case lNum && ratIs(lv, 1):
+			return num(1)
+		}
+		// (u^a)^b = u^(a*b) для числовых показателей
+		if inner, ok := l.(*BinaryOpNode); ok && (inner.Operator == "^" || inner.Operator == "**") && rNum {
+			if iv, ok := constValue(inner.Right); ok {
+				return simplifyBinary(inner.Left, "^", ratNode(new(big.Rat).Mul(iv, rv)))
+			}
+		}
+		return binary(l, "^", r)
+	}
+	return binary(l, op, r)
+}
+
+// maxFoldBits ограничивает размер свёрнутых констант, чтобы 10^1000 оставалось степенью
+const maxFoldBits = 512
+
+// constValue возвращает точное значение числа или дроби из целых чисел: 2, 0.5, 1/3, -2/3
+func constValue(n Node) (*big.Rat, bool) {
+	switch v := n.(type) {
+	case *NumberNode:
+		r, err := v.exact()
+		return r, err == nil
+	case *BinaryOpNode:
+		if inner, ok := negated(v); ok {
+			if r, ok := constValue(inner); ok {
+				return r.Neg(r), true
+			}
+			return nil, false
+		}
+		a, ok1 := v.Left.(*NumberNode)
+		b, ok2 := v.Right.(*NumberNode)
+		if v.Operator == "/" && ok1 && ok2 && a.IsInt && b.IsInt && b.Int != 0 {
+			return big.NewRat(a.Int, b.Int), true
+		}
+	}
+	return nil, false
+}
+
+// ratNode записывает точное число узлом: целое — числом, остальные — дробью 1/3
+func ratNode(r *big.Rat) Node {
+	if r.IsInt() {
+		return intNode(r.Num())
+	}
+	frac := binary(intNode(new(big.Int).Abs(r.Num())), "/", intNode(r.Denom()))
+	if r.Sign() < 0 {
+		return neg(frac)
+	}
+	return frac
+}
+
+func intNode(n *big.Int) *NumberNode {
+	node, err := integerNode(n, n.String())
+	if err != nil {
+		// Вне диапазона float64: точное значение остаётся в Text
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return &NumberNode{Val: f, Text: n.String()}
+	}
+	return node
+}
+
+func ratIs(r *big.Rat, n int64) bool {
+	return r.IsInt() && r.Num().IsInt64() && r.Num().Int64() == n
+}
+
+// smallInt сообщает, что показатель — небольшое целое, степень с которым можно вычислить точно
+func smallInt(r *big.Rat) bool {
+	return r.IsInt() && r.Num().BitLen() <= 10
+}
+
+// foldConstants вычисляет операцию над точными числами; дробная степень не сворачивается: 2^(1/2)
+func foldConstants(a *big.Rat, op string, b *big.Rat) (*big.Rat, bool) {
+	var result *big.Rat
+	switch op {
+	case "+":
+		result = new(big.Rat).Add(a, b)
+	case "-":
+		result = new(big.Rat).Sub(a, b)
+	case "*":
+		result = new(big.Rat).Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return nil, false
+		}
+		result = new(big.Rat).Quo(a, b)
+	case "^", "**":
+		if !smallInt(b) {
+			return nil, false
+		}
+		var err error
+		if result, err = ratIntPow(a, b.Num()); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	if result.Num().BitLen() > maxFoldBits || result.Denom().BitLen() > maxFoldBits {
+		return nil, false
+	}
+	return result, true
+}
+
+// term — слагаемое coef*rest; rest == nil для числа
+type term struct {
+	coef *big.Rat
+	rest Node
+}
+
+// simplifySum собирает подобные слагаемые: x + 2*x - 1 + 3 = 3*x + 2, x/3 + x/6 = x/2
+func simplifySum(node Node) Node {
+	var terms []term
+	collectTerms(node, 1, &terms)
+
+	var order []string
+	groups := make(map[string]*term)
+	constant := new(big.Rat)
+	for _, t := range terms {
+		if t.rest == nil {
+			constant.Add(constant, t.coef)
+			continue
+		}
+		key := termKey(t.rest)
+		if g, ok := groups[key]; ok {
+			g.coef.Add(g.coef, t.coef)
+			continue
+		}
+		groups[key] = &term{coef: new(big.Rat).Set(t.coef), rest: t.rest}
+		order = append(order, key)
+	}
+
+	parts := make([]term, 0, len(order)+1)
+	for _, key := range order {
+		if g := groups[key]; g.coef.Sign() != 0 {
+			parts = append(parts, *g)
+		}
+	}
+	if constant.Sign() != 0 {
+		parts = append(parts, term{coef: constant})
+	}
+	// Сумма начинается с положительного слагаемого, если оно есть: 1 - log(x), а не -log(x) + 1
+	for i, t := range parts {
+		if t.coef.Sign() > 0 {
+			copy(parts[1:i+1], parts[:i])
+			parts[0] = t
+			break
+		}
+	}
+
+	var result Node
+	for _, t := range parts {
+		abs := new(big.Rat).Abs(t.coef)
+		part := t.rest
+		switch {
+		case t.rest == nil:
+			part = ratNode(abs)
+		case !ratIs(abs, 1):
+			part = simplifyProduct(binary(ratNode(abs), "*", t.rest))
+		}
+		switch {
+		case result == nil && t.coef.Sign() < 0:
+			result = neg(part)
+		case result == nil:
+			result = part
+		case t.coef.Sign() < 0:
+			result = binary(result, "-", part)
+		default:
+			result = binary(result, "+", part)
+		}
+	}
+	if result == nil {
+		return num(0)
+	}
+	return result
+}
+
+// termKey — ключ подобных слагаемых, не зависящий от порядка множителей: x*y и y*x подобны
+func termKey(rest Node) string {
+	coef, factors := productFactors(rest)
+	keys := make([]string, len(factors))
+	for i, f := range factors {
+		keys[i] = FormatNode(power(f.base, f.power))
+	}
+	sort.Strings(keys)
+	return coef.RatString() + "*" + strings.Join(keys, "*")
+}
+
+// collectTerms раскладывает сумму на слагаемые; у произведений и дробей
+// числовой коэффициент отделяется от остальных множителей: 2*x/3 = 2/3 * x
+func collectTerms(node Node, sign int64, terms *[]term) {
+	if b, ok := node.(*BinaryOpNode); ok && (b.Operator == "+" || b.Operator == "-") {
+		collectTerms(b.Left, sign, terms)
+		if b.Operator == "-" {
+			sign = -sign
+		}
+		collectTerms(b.Right, sign, terms)
+		return
+	}
+	if c, ok := constValue(node); ok {
+		*terms = append(*terms, term{coef: c.Mul(c, big.NewRat(sign, 1))})
+		return
+	}
+	if b, ok := node.(*BinaryOpNode); ok && (b.Operator == "*" || b.Operator == "/") {
+		coef, factors := productFactors(node)
+		var rest Node
+		if len(factors) > 0 && coef.Sign() != 0 {
+			rest = buildProduct(big.NewRat(1, 1), factors)
+		}
+		*terms = append(*terms, term{coef: coef.Mul(coef, big.NewRat(sign, 1)), rest: rest})
+		return
+	}
+	*terms = append(*terms, term{coef: big.NewRat(sign, 1), rest: node})
+}
+
+// factor — множитель base^power
+type factor struct {
+	base  Node
+	power *big.Rat
+}
+
+// simplifyProduct сворачивает числовые множители, собирает степени и сокращает дроби:
+// 2*x*3*x = 6*x^2, 2*x/(4*x^3) = 1/(2*x^2), x/(x*y)^2 = 1/(x*y^2)
+func simplifyProduct(node Node) Node {
+	coef, factors := productFactors(node)
+	if coef.Sign() == 0 {
+		return num(0)
+	}
+	return buildProduct(coef, factors)
+}
+
+// productFactors раскладывает произведение на числовой коэффициент и степени;
+// степени одного основания складываются, сократившиеся множители пропадают
+func productFactors(node Node) (*big.Rat, []factor) {
+	coef := big.NewRat(1, 1)
+	var collected []factor
+	collectFactors(node, big.NewRat(1, 1), coef, &collected)
+
+	var order []string
+	groups := make(map[string]*factor)
+	for _, f := range collected {
+		key := FormatNode(f.base)
+		if g, ok := groups[key]; ok {
+			g.power.Add(g.power, f.power)
+			continue
+		}
+		groups[key] = &factor{base: f.base, power: new(big.Rat).Set(f.power)}
+		order = append(order, key)
+	}
+
+	factors := make([]factor, 0, len(order))
+	for _, key := range order {
+		if g := groups[key]; g.power.Sign() != 0 {
+			factors = append(factors, *g)
+		}
+	}
+	return coef, factors
+}
+
+// buildProduct записывает coef*множители дробью: числитель из положительных степеней, знаменатель — из отрицательных
+func buildProduct(coef *big.Rat, factors []factor) Node {
+	top := productOf(new(big.Int).Abs(coef.Num()), nil)
+	bottom := productOf(coef.Denom(), nil)
+	for _, f := range factors {
+		if f.power.Sign() > 0 {
+			top = append(top, power(f.base, f.power))
+		} else {
+			bottom = append(bottom, power(f.base, new(big.Rat).Neg(f.power)))
+		}
+	}
+
+	result := joinProduct(top)
+	if len(bottom) > 0 {
+		if result == nil {
+			result = num(1)
+		}
+		result = binary(result, "/", joinProduct(bottom))
+	}
+	if result == nil {
+		result = num(1)
+	}
+	if coef.Sign() < 0 {
+		return neg(result)
+	}
+	return result
+}
+
+// productOf добавляет числовой множитель, если он отличен от единицы
+func productOf(coef *big.Int, parts []Node) []Node {
+	if !coef.IsInt64() || coef.Int64() != 1 {
+		return append([]Node{intNode(coef)}, parts...)
+	}
+	return parts
+}
+
+func power(base Node, p *big.Rat) Node {
+	if ratIs(p, 1) {
+		return base
+	}
+	return binary(base, "^", ratNode(p))
+}
+
+func joinProduct(parts []Node) Node {
+	var product Node
+	for _, part := range parts {
+		if product == nil {
+			product = part
+		} else {
+			product = binary(product, "*", part)
+		}
+	}
+	return product
+}
+
+// collectFactors раскладывает произведение и частное на числовой коэффициент и степени;
+// p — показатель, в который возводится node (-1 для знаменателя)
+func collectFactors(node Node, p *big.Rat, coef *big.Rat, factors *[]factor) {
+	if b, ok := node.(*BinaryOpNode); ok {
+		switch b.Operator {
+		case "*":
+			collectFactors(b.Left, p, coef, factors)
+			collectFactors(b.Right, p, coef, factors)
+			return
+		case "/":
+			collectFactors(b.Left, p, coef, factors)
+			collectFactors(b.Right, new(big.Rat).Neg(p), coef, factors)
+			return
+		}
+	}
+	if c, ok := constValue(node); ok && smallInt(p) && (c.Sign() != 0 || p.Sign() > 0) {
+		if v, err := ratIntPow(c, p.Num()); err == nil {
+			coef.Mul(coef, v)
+			return
+		}
+	}
+	if inner, ok := negated(node); ok && smallInt(p) {
+		if p.Num().Bit(0) == 1 {
+			coef.Neg(coef)
+		}
+		collectFactors(inner, p, coef, factors)
+		return
+	}
+	if b, ok := node.(*BinaryOpNode); ok && (b.Operator == "^" || b.Operator == "**") {
+		if e, ok := constValue(b.Right); ok {
+			exp := new(big.Rat).Mul(p, e)
+			// (x*y)^n = x^n*y^n — только для целого n: sqrt(x*y) не всегда равен sqrt(x)*sqrt(y)
+			if inner, ok := b.Left.(*BinaryOpNode); ok && smallInt(exp) && smallInt(e) && (inner.Operator == "*" || inner.Operator == "/") {
+				collectFactors(inner, exp, coef, factors)
+				return
+			}
+			*factors = append(*factors, factor{base: b.Left, power: exp})
+			return
+		}
+	}
+	*factors = append(*factors, factor{base: node, power: new(big.Rat).Set(p)})
+}
+
+// Приоритеты операций при выводе: чем больше, тем сильнее связывает
+const (
+	precLowest = iota
+	precSum
+	precProduct
+	precUnary
+	precPower
+	precAtom
+)
+
+// FormatNode записывает выражение в инфиксной форме с минимумом скобок
+func FormatNode(node Node) string {
+	text, _ := formatNode(node)
+	return text
+}
+
+func formatNode(node Node) (string, int) {
+	switch n := node.(type) {
+	case *NumberNode:
+		var text string
+		if n.IsInt {
+			text = strconv.FormatInt(n.Int, 10)
+		} else {
+			text = strconv.FormatFloat(n.Val, 'g', -1, 64)
+		}
+		if n.Val < 0 {
+			return text, precUnary
+		}
+		return text, precAtom
+	case *VariableNode:
+		return n.Name, precAtom
+	case *StringNode:
+		return strconv.Quote(n.Val), precAtom
+	case *CallNode:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = FormatNode(arg)
+		}
+		return n.Name + "(" + strings.Join(args, ", ") + ")", precAtom
+	case *ListNode:
+		elements := make([]string, len(n.Elements))
+		for i, el := range n.Elements {
+			elements[i] = FormatNode(el)
+		}
+		return "[" + strings.Join(elements, ", ") + "]", precAtom
+	case *BinaryOpNode:
+		return formatBinary(n)
+	case *EquationNode:
+		return FormatNode(n.Left) + " = " + FormatNode(n.Right), precLowest
+	default:
+		return fmt.Sprintf("<%T>", node), precAtom
+	}
+}
+
+func formatBinary(n *BinaryOpNode) (string, int) {
+	if inner, ok := negated(n); ok {
+		// -a*b и -(a*b) равны, поэтому произведение скобок не требует
+		text, prec := formatNode(inner)
+		if prec < precProduct || prec == precUnary {
+			text = "(" + text + ")"
+		}
+		return "-" + text, precUnary
+	}
+
+	op := n.Operator
+	left, leftPrec := formatNode(n.Left)
+	right := n.Right
+
+	switch op {
+	case "+", "-":
+		// a + -b записывается как a - b
+		if inner, ok := negativePart(right); ok {
+			right = inner
+			op = map[string]string{"+": "-", "-": "+"}[op]
+		}
+		rightText, rightPrec := formatNode(right)
+		if rightPrec < precSum || (op == "-" && rightPrec == precSum) {
+			rightText = "(" + rightText + ")"
+		}
+		return left + " " + op + " " + rightText, precSum
+	case "*", "/", "%", "//":
+		if leftPrec < precProduct {
+			left = "(" + left + ")"
+		}
+		rightText, rightPrec := formatNode(right)
+		sameAssociative := op == "*" && rightPrec == precProduct && right.(*BinaryOpNode).Operator == "*"
+		if rightPrec < precProduct || rightPrec == precUnary || (rightPrec == precProduct && !sameAssociative) {
+			rightText = "(" + rightText + ")"
+		}
+		return left + op + rightText, precProduct
+	case "^", "**":
+		if leftPrec <= precPower {
+			left = "(" + left + ")"
+		}
+		rightText, rightPrec := formatNode(right)
+		if rightPrec < precUnary {
+			rightText = "(" + rightText + ")"
+		}
+		return left + "^" + rightText, precPower
+	default:
+		if leftPrec < precAtom {
+			left = "(" + left + ")"
+		}
+		rightText, rightPrec := formatNode(right)
+		if rightPrec < precAtom {
+			rightText = "(" + rightText + ")"
+		}
+		return left + " " + op + " " + rightText, precLowest
+	}
+}
+
+// negativePart возвращает -x для узла x, если x — отрицательное число или унарный минус
+func negativePart(n Node) (Node, bool) {
+	if inner, ok := negated(n); ok {
+		return inner, true
+	}
+	if v, ok := numberValue(n); ok && v < 0 {
+		return num(-v), true
+	}
+	return nil, false
+}
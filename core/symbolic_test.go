@@ -0,0 +1,105 @@
+package core
+
+import "testing"
+
+func parseExpr(t *testing.T, input string) Node {
+	t.Helper()
+	node, err := NewParser(input).ParseExpression()
+	if err != nil {
+		t.Fatalf("%q: %v", input, err)
+	}
+	return node
+}
+
+func TestFormatNode(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"x - (y - z)", "x - (y - z)"},
+		{"(x - y) - z", "x - y - z"},
+		{"x + (y + z)", "x + y + z"},
+		{"x + (y - z)", "x + y - z"},
+		{"x + -y", "x - y"},
+		{"x - -y", "x + y"},
+		{"(a^b)^c", "(a^b)^c"},
+		{"a^b^c", "a^b^c"},
+		{"a^(b^c)", "a^b^c"},
+		{"(-a)^2", "(-a)^2"},
+		{"-a^2", "-a^2"},
+		{"-(a*b)", "-a*b"},
+		{"-(a + b)", "-(a + b)"},
+		{"a/(b*c)", "a/(b*c)"},
+		{"(a*b)/c", "a*b/c"},
+		{"a*(b*c)", "a*b*c"},
+		{"a*(b/c)", "a*(b/c)"},
+		{"2*(x + 1)", "2*(x + 1)"},
+		{"x*-y", "x*(-y)"},
+		{"(x + 1)^-1", "(x + 1)^-1"},
+		{"sin(x)^2", "sin(x)^2"},
+	}
+	for _, tt := range tests {
+		if got := FormatNode(parseExpr(t, tt.input)); got != tt.want {
+			t.Errorf("FormatNode(%q) = %q, ожидалось %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDerivative(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"5", "0"},
+		{"y*x", "y"},
+		{"x^3", "3*x^2"},
+		{"3*x^2 + 2*x + 1", "6*x + 2"},
+		{"sin(x)", "cos(x)"},
+		{"x*sin(x)", "sin(x) + x*cos(x)"},
+		{"exp(2*x)", "2*exp(2*x)"},
+		{"log(x)", "1/x"},
+		{"1/x", "-1/x^2"},
+		{"sqrt(x)", "1/(2*sqrt(x))"},
+		{"tan(x)", "1/cos(x)^2"},
+		{"2^x", "2^x*log(2)"},
+		{"x^x", "x^x*(log(x) + 1)"},
+		{"(x + 1)/(x - 1)", "-2/(x - 1)^2"},
+		{"x*y*x + y*x^2", "4*y*x"},
+	}
+	env := NewEnv(map[string]Value{}, map[string]*Function{})
+	for _, tt := range tests {
+		d, err := (&differ{x: "x", env: env}).derive(parseExpr(t, tt.input))
+		if err != nil {
+			t.Errorf("diff(%s, x): %v", tt.input, err)
+			continue
+		}
+		if got := FormatNode(Simplify(d)); got != tt.want {
+			t.Errorf("diff(%s, x) = %s, ожидалось %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSimplifyLikeTerms(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"x + x", "2*x"},
+		{"2*x + 3*x", "5*x"},
+		{"3*x - x", "2*x"},
+		{"x - 2*x", "-x"},
+		{"x - x", "0"},
+		{"a + b - a", "b"},
+		{"x + 1 + x + 2", "2*x + 3"},
+		{"x^2 + 2*x^2", "3*x^2"},
+		{"x/3 + x/6", "x/2"},
+		{"(1/2)*x + x/2", "x"},
+		{"x*y + y*x", "2*x*y"},
+		{"(2*x)^2 + x^2", "(2*x)^2 + x^2"},
+		{"x*1 + 0", "x"},
+		{"x*x^2", "x^3"},
+		{"2*x/4", "x/2"},
+	}
+	for _, tt := range tests {
+		if got := FormatNode(Simplify(parseExpr(t, tt.input))); got != tt.want {
+			t.Errorf("Simplify(%s) = %s, ожидалось %s", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// dimension — степени основных величин: длина (m), масса (kg), время (s),
+// ток (A), температура (K), количество вещества (mol), сила света (cd), объём данных (B)
+type dimension [8]int
+
+var (
+	dimLength     = dimension{1}
+	dimMass       = dimension{0, 1}
+	dimTime       = dimension{0, 0, 1}
+	dimCurrent    = dimension{0, 0, 0, 1}
+	dimTemp       = dimension{0, 0, 0, 0, 1}
+	dimAmount     = dimension{0, 0, 0, 0, 0, 1}
+	dimLuminous   = dimension{0, 0, 0, 0, 0, 0, 1}
+	dimData       = dimension{0, 0, 0, 0, 0, 0, 0, 1}
+	dimArea       = dimension{2}
+	dimVolume     = dimension{3}
+	dimVelocity   = dimension{1, 0, -1}
+	dimFrequency  = dimension{0, 0, -1}
+	dimForce      = dimension{1, 1, -2}
+	dimEnergy     = dimension{2, 1, -2}
+	dimPower      = dimension{2, 1, -3}
+	dimPressure   = dimension{-1, 1, -2}
+	dimCharge     = dimension{0, 0, 1, 1}
+	dimVoltage    = dimension{2, 1, -3, -1}
+	dimResistance = dimension{2, 1, -3, -2}
+)
+
+var baseUnitNames = [len(dimension{})]string{"m", "kg", "s", "A", "K", "mol", "cd", "B"}
+
+// unitDef — единица измерения: значение в SI = значение * factor + offset
+type unitDef struct {
+	factor float64
+	offset float64 // ненулевое только у шкал температуры degC и degF
+	dim    dimension
+}
+
+var units = map[string]unitDef{
+	// Длина
+	"cm":  {factor: 1e-2, dim: dimLength},
+	"in":  {factor: 0.0254, dim: dimLength},
+	"ft":  {factor: 0.3048, dim: dimLength},
+	"yd":  {factor: 0.9144, dim: dimLength},
+	"mi":  {factor: 1609.344, dim: dimLength},
+	"nmi": {factor: 1852, dim: dimLength},
+	// Масса
+	"t":  {factor: 1000, dim: dimMass},
+	"lb": {factor: 0.45359237, dim: dimMass},
+	"oz": {factor: 0.028349523125, dim: dimMass},
+	// Время
+	"min":  {factor: 60, dim: dimTime},
+	"h":    {factor: 3600, dim: dimTime},
+	"d":    {factor: 86400, dim: dimTime},
+	"day":  {factor: 86400, dim: dimTime},
+	"week": {factor: 604800, dim: dimTime},
+	"yr":   {factor: 31557600, dim: dimTime}, // юлианский год
+	// Площадь и объём
+	"ha":   {factor: 1e4, dim: dimArea},
+	"acre": {factor: 4046.8564224, dim: dimArea},
+	"gal":  {factor: 3.785411784e-3, dim: dimVolume},
+	// Скорость
+	"mph": {factor: 1609.344 / 3600, dim: dimVelocity},
+	"kph": {factor: 1000.0 / 3600, dim: dimVelocity},
+	"kn":  {factor: 1852.0 / 3600, dim: dimVelocity},
+	// Сила, энергия, мощность, давление
+	"lbf":  {factor: 4.4482216152605, dim: dimForce},
+	"cal":  {factor: 4.184, dim: dimEnergy},
+	"kcal": {factor: 4184, dim: dimEnergy},
+	"hp":   {factor: 745.69987158227022, dim: dimPower},
+	"bar":  {factor: 1e5, dim: dimPressure},
+	"atm":  {factor: 101325, dim: dimPressure},
+	"psi":  {factor: 6894.757293168, dim: dimPressure},
+	// Электричество
+	"C":   {factor: 1, dim: dimCharge},
+	"ohm": {factor: 1, dim: dimResistance},
+	// Температура, количество вещества, сила света
+	"K":    {factor: 1, dim: dimTemp},
+	"degC": {factor: 1, offset: 273.15, dim: dimTemp},
+	"degF": {factor: 5.0 / 9, offset: 273.15 - 32*5.0/9, dim: dimTemp},
+	"mol":  {factor: 1, dim: dimAmount},
+	"cd":   {factor: 1, dim: dimLuminous},
+	// Объём данных: десятичные и двоичные приставки
+	"B":    {factor: 1, dim: dimData},
+	"bit":  {factor: 0.125, dim: dimData},
+	"kbit": {factor: 125, dim: dimData},
+	"Mbit": {factor: 125e3, dim: dimData},
+	"Gbit": {factor: 125e6, dim: dimData},
+	"kB":   {factor: 1e3, dim: dimData},
+	"KB":   {factor: 1e3, dim: dimData},
+	"MB":   {factor: 1e6, dim: dimData},
+	"GB":   {factor: 1e9, dim: dimData},
+	"TB":   {factor: 1e12, dim: dimData},
+	"KiB":  {factor: 1 << 10, dim: dimData},
+	"MiB":  {factor: 1 << 20, dim: dimData},
+	"GiB":  {factor: 1 << 30, dim: dimData},
+	"TiB":  {factor: 1 << 40, dim: dimData},
+}
+
+// Единицы SI, к которым допускаются десятичные приставки: km, ms, kW, MHz...
+var prefixedUnits = map[string]unitDef{
+	"m":  {factor: 1, dim: dimLength},
+	"g":  {factor: 1e-3, dim: dimMass},
+	"s":  {factor: 1, dim: dimTime},
+	"L":  {factor: 1e-3, dim: dimVolume},
+	"N":  {factor: 1, dim: dimForce},
+	"J":  {factor: 1, dim: dimEnergy},
+	"Wh": {factor: 3600, dim: dimEnergy},
+	"eV": {factor: 1.602176634e-19, dim: dimEnergy},
+	"W":  {factor: 1, dim: dimPower},
+	"Pa": {factor: 1, dim: dimPressure},
+	"Hz": {factor: 1, dim: dimFrequency},
+	"A":  {factor: 1, dim: dimCurrent},
+	"V":  {factor: 1, dim: dimVoltage},
+}
+
+var siPrefixes = map[string]float64{
+	"n": 1e-9,
+	"u": 1e-6,
+	"m": 1e-3,
+	"k": 1e3,
+	"M": 1e6,
+	"G": 1e9,
+}
+
+func init() {
+	for name, def := range prefixedUnits {
+		units[name] = def
+		for prefix, scale := range siPrefixes {
+			units[prefix+name] = unitDef{factor: def.factor * scale, dim: def.dim}
+		}
+	}
+}
+
+func isUnitName(name string) bool {
+	_, ok := units[name]
+	return ok
+}
+
+// unitTerm — сомножитель составной единицы со степенью: s^-2
+type unitTerm struct {
+	Name  string
+	Power int
+}
+
+// Unit — единица измерения, возможно составная: km/h, kg*m/s^2
+type Unit struct {
+	terms []unitTerm
+}
+
+func (u Unit) factor() float64 {
+	f := 1.0
+	for _, t := range u.terms {
+		f *= math.Pow(units[t.Name].factor, float64(t.Power))
+	}
+	return f
+}
+
+func (u Unit) dim() dimension {
+	var d dimension
+	for _, t := range u.terms {
+		def := units[t.Name]
+		for i := range d {
+			d[i] += def.dim[i] * t.Power
+		}
+	}
+	return d
+}
+
+// offset — смещение шкалы; имеет смысл только для единицы из одного сомножителя
+func (u Unit) offset() float64 {
+	if len(u.terms) == 1 && u.terms[0].Power == 1 {
+		return units[u.terms[0].Name].offset
+	}
+	return 0
+}
+
+func (u Unit) hasOffset() bool {
+	for _, t := range u.terms {
+		if units[t.Name].offset != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (u Unit) toSI(x float64) float64 {
+	return x*u.factor() + u.offset()
+}
+
+// fromSI переводит значение из SI. Вычитание смещения шкалы оставляет ошибку
+// округления вроде 5.7e-14 там, где должен быть ноль (32 degF в degC): разность
+// меньше точности самого смещения считаем нулём
+func (u Unit) fromSI(x float64) float64 {
+	offset := u.offset()
+	d := x - offset
+	if offset != 0 && math.Abs(d) < 1e-14*math.Abs(offset) {
+		d = 0
+	}
+	return d / u.factor()
+}
+
+// combine перемножает единицы (sign = 1) или делит u на other (sign = -1)
+func (u Unit) combine(other Unit, sign int) Unit {
+	terms := append([]unitTerm{}, u.terms...)
+	for _, t := range other.terms {
+		merged := false
+		for i := range terms {
+			if terms[i].Name == t.Name {
+				terms[i].Power += sign * t.Power
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			terms = append(terms, unitTerm{Name: t.Name, Power: sign * t.Power})
+		}
+	}
+	return Unit{terms: withoutZeroPowers(terms)}
+}
+
+func (u Unit) pow(n int) Unit {
+	terms := make([]unitTerm, len(u.terms))
+	for i, t := range u.terms {
+		terms[i] = unitTerm{Name: t.Name, Power: t.Power * n}
+	}
+	return Unit{terms: withoutZeroPowers(terms)}
+}
+
+func withoutZeroPowers(terms []unitTerm) []unitTerm {
+	result := terms[:0]
+	for _, t := range terms {
+		if t.Power != 0 {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// String записывает единицу так, как её можно ввести: kg*m/s^2, s^-1
+func (u Unit) String() string {
+	var num, den []string
+	for _, t := range u.terms {
+		switch {
+		case t.Power == 1:
+			num = append(num, t.Name)
+		case t.Power > 1:
+			num = append(num, fmt.Sprintf("%s^%d", t.Name, t.Power))
+		case t.Power == -1:
+			den = append(den, t.Name)
+		default:
+			den = append(den, fmt.Sprintf("%s^%d", t.Name, -t.Power))
+		}
+	}
+	if len(num) == 0 {
+		parts := make([]string, len(u.terms))
+		for i, t := range u.terms {
+			parts[i] = fmt.Sprintf("%s^%d", t.Name, t.Power)
+		}
+		return strings.Join(parts, "*")
+	}
+	return strings.Join(append([]string{strings.Join(num, "*")}, den...), "/")
+}
+
+// dimensionString описывает размерность через основные единицы SI: kg*m/s^2
+func dimensionString(d dimension) string {
+	var u Unit
+	for i, power := range d {
+		if power != 0 {
+			u.terms = append(u.terms, unitTerm{Name: baseUnitNames[i], Power: power})
+		}
+	}
+	if len(u.terms) == 0 {
+		return "безразмерная"
+	}
+	return u.String()
+}
+
+// Quantity — число с единицей измерения. Value хранится в основных единицах SI,
+// Unit определяет, в каких единицах выводится результат.
+type Quantity struct {
+	Value float64
+	Unit  Unit
+}
+
+// FormatQuantity выводит величину в её единицах: 5.3 km
+func FormatQuantity(q Quantity) string {
+	return formatQuantity(q, 12)
+}
+
+func formatQuantity(q Quantity, digits int) string {
+	return strconv.FormatFloat(q.Unit.fromSI(q.Value), 'g', digits, 64) + " " + q.Unit.String()
+}
+
+// ParseQuantity восстанавливает величину из текста вида "5.3 km"
+func ParseQuantity(text string) (Quantity, error) {
+	parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	if len(parts) != 2 {
+		return Quantity{}, fmt.Errorf("некорректная величина: %q", text)
+	}
+	x, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Quantity{}, fmt.Errorf("некорректная величина: %q", text)
+	}
+	p := NewParser(parts[1])
+	unit, err := p.parseUnit(false)
+	if err != nil {
+		return Quantity{}, err
+	}
+	if p.currentToken.Type != TokenEOF || p.lexer.Err() != nil {
+		return Quantity{}, fmt.Errorf("некорректная единица: %q", parts[1])
+	}
+	return Quantity{Value: unit.toSI(x), Unit: unit}, nil
+}
+
+// toQuantity приводит число к безразмерной величине
+func toQuantity(v Value) (Quantity, bool) {
+	if q, ok := v.(Quantity); ok {
+		return q, true
+	}
+	f, ok := toFloat(v)
+	return Quantity{Value: f}, ok
+}
+
+// QuantityNode — числовой литерал с единицей: 5 km, 12 kg*m/s^2
+type QuantityNode struct {
+	Expr Node
+	Unit Unit
+}
+
+func (n *QuantityNode) Value(env *Env) (Value, error) {
+	val, err := n.Expr.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	x, ok := toFloat(val)
+	if !ok {
+		return nil, fmt.Errorf("единицу %s можно указать только у числа", n.Unit)
+	}
+	return Quantity{Value: n.Unit.toSI(x), Unit: n.Unit}, nil
+}
+
+// ConvertNode — перевод в другие единицы: x to mph
+type ConvertNode struct {
+	Expr Node
+	Unit Unit
+}
+
+func (n *ConvertNode) Value(env *Env) (Value, error) {
+	val, err := n.Expr.Value(env)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := val.(Duration); ok {
+		val = durationQuantity(d)
+	}
+	q, ok := val.(Quantity)
+	if !ok {
+		return nil, fmt.Errorf("перевести в %s можно только величину с единицами, получено %s", n.Unit, val)
+	}
+	if q.Unit.dim() != n.Unit.dim() {
+		return nil, fmt.Errorf("нельзя перевести %s в %s: размерности %s и %s", q.Unit, n.Unit,
+			dimensionString(q.Unit.dim()), dimensionString(n.Unit.dim()))
+	}
+	return Quantity{Value: q.Value, Unit: n.Unit}, nil
+}
+
+// parseConversion разбирает выражение с необязательным переводом единиц: expr to km/h
+func (p *Parser) parseConversion() (Node, error) {
+	node, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentToken.Type == TokenIdentifier && p.currentToken.Value == "to" {
+		p.nextToken()
+		unit, err := p.parseUnit(false)
+		if err != nil {
+			return nil, err
+		}
+		node = &ConvertNode{Expr: node, Unit: unit}
+	}
+	return node, nil
+}
+
+// parseUnit разбирает единицу: km, m/s^2, kg*m/s^2. После числового литерала
+// (literal = true) знаки '*', '/' и '^' относятся к единице, только если записаны
+// без пробелов: "12 kg*m/s^2" — единица, а "2 kg * m" — умножение на переменную m.
+func (p *Parser) parseUnit(literal bool) (Unit, error) {
+	first := p.currentToken
+	var unit Unit
+	sign := 1
+	for {
+		if p.currentToken.Type != TokenIdentifier || !isUnitName(p.currentToken.Value) {
+			return Unit{}, p.errorAt(p.currentToken, []string{"единица измерения"}, "неизвестная единица измерения: %s", describeToken(p.currentToken))
+		}
+		term := unitTerm{Name: p.currentToken.Value, Power: sign}
+		end := p.currentToken.End
+		p.nextToken()
+
+		if p.currentToken.Type == TokenPower && (!literal || p.currentToken.Pos == end) {
+			p.nextToken()
+			exponent := 1
+			if p.currentToken.Type == TokenMinus {
+				exponent = -1
+				p.nextToken()
+			}
+			n, err := strconv.Atoi(p.currentToken.Value)
+			if p.currentToken.Type != TokenNumber || err != nil {
+				return Unit{}, p.errorAt(p.currentToken, []string{"целое число"}, "показатель степени единицы должен быть целым числом")
+			}
+			term.Power *= exponent * n
+			end = p.currentToken.End
+			p.nextToken()
+		}
+		unit = unit.combine(Unit{terms: []unitTerm{term}}, 1)
+
+		if p.currentToken.Type != TokenMultiply && p.currentToken.Type != TokenDivide {
+			break
+		}
+		if literal && (p.currentToken.Pos != end || p.peekToken.Pos != end+1 ||
+			p.peekToken.Type != TokenIdentifier || !isUnitName(p.peekToken.Value)) {
+			break
+		}
+		sign = 1
+		if p.currentToken.Type == TokenDivide {
+			sign = -1
+		}
+		p.nextToken()
+	}
+
+	if len(unit.terms) == 0 {
+		return Unit{}, p.errorSpan(first, "единица измерения сокращается до безразмерной")
+	}
+	if unit.hasOffset() && (len(unit.terms) != 1 || unit.terms[0].Power != 1) {
+		return Unit{}, p.errorSpan(first, "degC и degF нельзя использовать в составных единицах, используйте K")
+	}
+	return unit, nil
+}
+
+// BinaryOp — операции с величинами; число считается безразмерной величиной
+func (q Quantity) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	if _, ok := toQuantity(other); !ok {
+		return nil, ErrUnsupported
+	}
+	left, right := operands(q, other, reversed)
+	return quantityArithmetic(left, op, right)
+}
+
+// quantityArithmetic выполняет операцию, в которой хотя бы один операнд — величина с единицами
+func quantityArithmetic(left Value, op string, right Value) (Value, error) {
+	l, ok1 := toQuantity(left)
+	r, ok2 := toQuantity(right)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("операция %s невозможна между %s и %s", op, left, right)
+	}
+
+	switch op {
+	case "+", "-", "%", "//":
+		// Ноль без единиц совместим с любой размерностью; унарный минус — это 0 - x
+		if len(l.Unit.terms) == 0 && l.Value == 0 && (op == "+" || op == "-") {
+			if op == "-" {
+				return Quantity{Value: r.Unit.toSI(-r.Unit.fromSI(r.Value)), Unit: r.Unit}, nil
+			}
+			return r, nil
+		}
+		if len(r.Unit.terms) == 0 && r.Value == 0 && (op == "+" || op == "-") {
+			return l, nil
+		}
+		if l.Unit.dim() != r.Unit.dim() {
+			return nil, fmt.Errorf("несовместимые единицы: %s и %s", quantityUnitName(l), quantityUnitName(r))
+		}
+		return sameDimensionOp(l, op, r)
+	case "*", "/":
+		if l.Unit.hasOffset() || r.Unit.hasOffset() {
+			return nil, errors.New("температуру в degC или degF нельзя умножать и делить, переведите её в K")
+		}
+		if op == "*" {
+			return quantityResult(l.Value*r.Value, l.Unit.combine(r.Unit, 1)), nil
+		}
+		if r.Value == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return quantityResult(l.Value/r.Value, l.Unit.combine(r.Unit, -1)), nil
+	case "^", "**":
+		if len(r.Unit.terms) != 0 {
+			return nil, errors.New("показатель степени не может иметь единицу измерения")
+		}
+		n, ok := toInt(right)
+		if !ok {
+			return nil, errors.New("величину с единицами можно возводить только в целую степень")
+		}
+		if l.Unit.hasOffset() && n != 1 {
+			return nil, errors.New("температуру в degC или degF нельзя возводить в степень, переведите её в K")
+		}
+		return quantityResult(math.Pow(l.Value, float64(n)), l.Unit.pow(int(n))), nil
+	default:
+		return nil, fmt.Errorf("операция %s не определена для величин с единицами", op)
+	}
+}
+
+func sameDimensionOp(l Quantity, op string, r Quantity) (Value, error) {
+	offsets := l.Unit.hasOffset() && r.Unit.hasOffset()
+	switch op {
+	case "+":
+		if offsets {
+			return nil, errors.New("нельзя складывать две температуры по шкале degC/degF, прибавляйте разницу в K")
+		}
+		return Quantity{Value: l.Value + r.Value, Unit: l.Unit}, nil
+	case "-":
+		if offsets {
+			// Разность температур — это интервал, он выражается в кельвинах
+			return Quantity{Value: l.Value - r.Value, Unit: Unit{terms: []unitTerm{{Name: "K", Power: 1}}}}, nil
+		}
+		return Quantity{Value: l.Value - r.Value, Unit: l.Unit}, nil
+	case "%":
+		if r.Value == 0 {
+			return nil, errors.New("деление по модулю на ноль")
+		}
+		return Quantity{Value: math.Mod(l.Value, r.Value), Unit: l.Unit}, nil
+	default: // "//"
+		if r.Value == 0 {
+			return nil, errors.New("деление на ноль")
+		}
+		return Number(math.Floor(l.Value / r.Value)), nil
+	}
+}
+
+// quantityResult возвращает число, если единицы сократились: 10 km / 5 m = 2000
+func quantityResult(value float64, unit Unit) Value {
+	if unit.dim() == (dimension{}) {
+		return Number(value)
+	}
+	return Quantity{Value: value, Unit: unit}
+}
+
+func quantityUnitName(q Quantity) string {
+	if len(q.Unit.terms) == 0 {
+		return "безразмерная величина"
+	}
+	return q.Unit.String()
+}
+
+// Compare сравнивает величины одной размерности; число — безразмерная величина
+func (q Quantity) Compare(other Value) (int, error) {
+	o, ok := toQuantity(other)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	if q.Unit.dim() != o.Unit.dim() {
+		return 0, fmt.Errorf("нельзя сравнить %s и %s: разные размерности", q, other)
+	}
+	return compareFloats(q.Value, o.Value), nil
+}
@@ -0,0 +1,760 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Value — значение выражения. Узлы дерева вычисляют Value, встроенные функции
+// принимают и возвращают Value, переменные хранят Value. Тип сам определяет своё
+// имя, запись, равенство, истинность и формат в файле состояния.
+//
+// Новый тип добавляется в одном месте — своей реализацией Value: операторы он
+// получает через Arithmetic и Ordered, особый вывод — через Displayer, а присвоить
+// его переменной можно после регистрации декодера в valueTypes.
+type Value interface {
+	TypeName() string
+	String() string
+	Equal(other Value) bool
+	Truthy() bool
+	json.Marshaler
+}
+
+type (
+	Number   float64
+	Integer  int64
+	Complex  complex128
+	String   string
+	Bool     bool
+	List     []Value
+	Map      map[string]Value
+	Null     struct{}
+	Time     time.Time
+	Duration time.Duration
+	// History — результат команды history: последние команды, начиная с самой старой
+	History []string
+)
+
+// Rational — точная дробь режима big; Digits — знаков после запятой при выводе (0 — по умолчанию)
+type Rational struct {
+	Rat    *big.Rat
+	Digits int
+}
+
+// Arithmetic — значение, которое само реализует бинарные операторы, кроме сравнений;
+// reversed — значение стоит справа. Если тип не умеет работать с other, BinaryOp
+// возвращает ErrUnsupported, и оператор предлагается другому операнду: так 2 + 0.5
+// считает Number, а 2 + [1, 2] — List.
+type Arithmetic interface {
+	Value
+	BinaryOp(op string, other Value, reversed bool) (Value, error)
+}
+
+// Ordered — значение, которое сравнивается операторами <, <=, >, >=. Compare возвращает
+// знак разности значения и other или ErrUnsupported, как BinaryOp.
+type Ordered interface {
+	Value
+	Compare(other Value) (int, error)
+}
+
+// Displayer — значение, которое выводится как результат иначе, чем String:
+// целые числа — в выбранной системе счисления, матрицы — по строкам.
+// Display возвращает строки вывода; base — система счисления для целых чисел.
+type Displayer interface {
+	Value
+	Display(base int) []string
+}
+
+// ErrUnsupported — тип не реализует операцию с таким операндом, см. Arithmetic
+var ErrUnsupported = errors.New("операция не поддерживается")
+
+// operands возвращает v и other в порядке записи в выражении
+func operands(v, other Value, reversed bool) (Value, Value) {
+	if reversed {
+		return other, v
+	}
+	return v, other
+}
+
+// valueTypes — декодеры по имени типа. Переменной можно присвоить только значение,
+// которое удаётся сохранить в файл и прочитать обратно.
+var valueTypes = make(map[string]func(data []byte) (Value, error))
+
+func init() {
+	// Декодеры списков и словарей сами обращаются к valueTypes, поэтому реестр заполняется здесь
+	for name, decode := range map[string]func(data []byte) (Value, error){
+		"number":   decodeNumber,
+		"integer":  decodeAs(func(n int64) Value { return Integer(n) }),
+		"rational": decodeRational,
+		"complex":  decodeComplex,
+		"string":   decodeAs(func(s string) Value { return String(s) }),
+		"bool":     decodeAs(func(b bool) Value { return Bool(b) }),
+		"list":     decodeList,
+		"map":      decodeMap,
+		"null":     func([]byte) (Value, error) { return Null{}, nil },
+		"quantity": decodeQuantity,
+		"time":     decodeTime,
+		"duration": decodeAs(func(ns int64) Value { return Duration(ns) }),
+	} {
+		valueTypes[name] = decode
+	}
+}
+
+// assignable сообщает, что значение можно хранить в переменной
+func assignable(v Value) bool {
+	_, ok := valueTypes[v.TypeName()]
+	return ok
+}
+
+// EncodeValue записывает значение вместе с именем типа: {"type": "number", "value": 2.5}
+func EncodeValue(v Value) ([]byte, error) {
+	data, err := v.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(taggedValue{Type: v.TypeName(), Value: data})
+}
+
+// DecodeValue восстанавливает значение, записанное EncodeValue
+func DecodeValue(data []byte) (Value, error) {
+	var tagged taggedValue
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+	return DecodeTyped(tagged.Type, tagged.Value)
+}
+
+// DecodeTyped восстанавливает значение типа typeName по его записи MarshalJSON
+func DecodeTyped(typeName string, data []byte) (Value, error) {
+	decode, ok := valueTypes[typeName]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный тип значения: %s", typeName)
+	}
+	return decode(data)
+}
+
+type taggedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeAs — декодер типа, который записывается значением JSON как есть
+func decodeAs[T any](wrap func(T) Value) func(data []byte) (Value, error) {
+	return func(data []byte) (Value, error) {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return wrap(v), nil
+	}
+}
+
+// === Числа ===
+
+func (n Number) TypeName() string { return "number" }
+func (n Number) String() string   { return strconv.FormatFloat(float64(n), 'g', -1, 64) }
+func (n Number) Truthy() bool     { return n != 0 }
+func (n Number) Equal(other Value) bool {
+	return numberEqual(n, other)
+}
+
+// MarshalJSON записывает дробную часть даже у целых (2.0), чтобы при чтении
+// число не стало Integer; NaN и бесконечности — строками
+func (n Number) MarshalJSON() ([]byte, error) {
+	f := float64(n)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return json.Marshal(n.String())
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return []byte(s), nil
+}
+
+func decodeNumber(data []byte) (Value, error) {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное число %q", text)
+		}
+		return Number(f), nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return Number(f), nil
+}
+
+func (n Integer) TypeName() string { return "integer" }
+func (n Integer) String() string   { return strconv.FormatInt(int64(n), 10) }
+func (n Integer) Truthy() bool     { return n != 0 }
+func (n Integer) Equal(other Value) bool {
+	return numberEqual(n, other)
+}
+func (n Integer) MarshalJSON() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+// Display выводит целые значения в системе счисления base
+func (n Number) Display(base int) []string {
+	f := float64(n)
+	if base != 10 && f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return []string{FormatInt(int64(f), base)}
+	}
+	return []string{n.String()}
+}
+
+func (n Integer) Display(base int) []string {
+	return []string{FormatInt(int64(n), base)}
+}
+
+func (r Rational) TypeName() string { return "rational" }
+func (r Rational) Truthy() bool     { return r.Rat.Sign() != 0 }
+func (r Rational) String() string   { return FormatRat(r.Rat, r.precision()) }
+func (r Rational) Equal(other Value) bool {
+	return numberEqual(r, other)
+}
+
+// precision — число знаков для вывода и неточных операций с дробью
+func (r Rational) precision() int {
+	if r.Digits == 0 {
+		return DefaultPrecision
+	}
+	return r.Digits
+}
+
+// MarshalJSON записывает дробь строкой "1/3"
+func (r Rational) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Rat.RatString())
+}
+
+func decodeRational(data []byte) (Value, error) {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("некорректная дробь %q", text)
+	}
+	return Rational{Rat: r}, nil
+}
+
+func (z Complex) TypeName() string { return "complex" }
+func (z Complex) String() string   { return FormatComplex(complex128(z)) }
+func (z Complex) Truthy() bool     { return z != 0 }
+func (z Complex) Equal(other Value) bool {
+	w, ok := toComplex(other)
+	return ok && complex128(z) == w
+}
+
+// MarshalJSON записывает комплексное число строкой "(1+2i)": в JSON нет такого типа
+func (z Complex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatComplex(complex128(z), 'g', -1, 128))
+}
+
+func decodeComplex(data []byte) (Value, error) {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	z, err := strconv.ParseComplex(text, 128)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное комплексное число %q", text)
+	}
+	return Complex(z), nil
+}
+
+// === Строки и логические значения ===
+
+func (s String) TypeName() string { return "string" }
+func (s String) String() string   { return string(s) }
+func (s String) Truthy() bool     { return s != "" }
+func (s String) Equal(other Value) bool {
+	o, ok := other.(String)
+	return ok && s == o
+}
+func (s String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(s))
+}
+
+func (b Bool) TypeName() string { return "bool" }
+func (b Bool) String() string   { return strconv.FormatBool(bool(b)) }
+func (b Bool) Truthy() bool     { return bool(b) }
+func (b Bool) Equal(other Value) bool {
+	o, ok := other.(Bool)
+	return ok && b == o
+}
+func (b Bool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bool(b))
+}
+
+// === Списки, словари, null ===
+
+func (l List) TypeName() string { return "list" }
+func (l List) String() string   { return FormatList(l) }
+func (l List) Truthy() bool     { return len(l) > 0 }
+
+// Equal сравнивает списки поэлементно
+func (l List) Equal(other Value) bool {
+	o, ok := other.(List)
+	if !ok || len(l) != len(o) {
+		return false
+	}
+	for i := range l {
+		if !l[i].Equal(o[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON записывает список JSON-массивом, см. encodeElement
+func (l List) MarshalJSON() ([]byte, error) {
+	return l.marshal(encodeElement)
+}
+
+func (l List) marshal(encode func(Value) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, el := range l {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := encode(el)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func decodeList(data []byte) (Value, error) {
+	return unmarshalList(data, decodeElement)
+}
+
+func unmarshalList(data []byte, decode func([]byte) (Value, error)) (Value, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	list := make(List, len(raw))
+	for i, el := range raw {
+		v, err := decode(el)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (m Map) TypeName() string { return "map" }
+func (m Map) Truthy() bool     { return len(m) > 0 }
+
+// String выводит словарь с ключами по алфавиту: {"a": 1, "b": [1, 2]}
+func (m Map) String() string {
+	keys := m.keys()
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		el := m[k].String()
+		if s, ok := m[k].(String); ok {
+			el = strconv.Quote(string(s))
+		}
+		parts[i] = strconv.Quote(k) + ": " + el
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (m Map) Equal(other Value) bool {
+	o, ok := other.(Map)
+	if !ok || len(m) != len(o) {
+		return false
+	}
+	for k, v := range m {
+		w, ok := o[k]
+		if !ok || !v.Equal(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON записывает словарь JSON-объектом, см. encodeElement
+func (m Map) MarshalJSON() ([]byte, error) {
+	return m.marshal(encodeElement)
+}
+
+func (m Map) marshal(encode func(Value) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		data, err := encode(m[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// BinaryOp объединяет словари: m + {"k": v}; при совпадении ключей побеждает правый
+func (m Map) BinaryOp(op string, other Value, reversed bool) (Value, error) {
+	o, ok := other.(Map)
+	if op != "+" || !ok {
+		return nil, ErrUnsupported
+	}
+	left, right := m, o
+	if reversed {
+		left, right = o, m
+	}
+	result := make(Map, len(left)+len(right))
+	for k, v := range left {
+		result[k] = v
+	}
+	for k, v := range right {
+		result[k] = v
+	}
+	return result, nil
+}
+
+func (m Map) keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decodeMap(data []byte) (Value, error) {
+	return unmarshalMap(data, decodeElement)
+}
+
+func unmarshalMap(data []byte, decode func([]byte) (Value, error)) (Value, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	m := make(Map, len(raw))
+	for k, el := range raw {
+		v, err := decode(el)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func (Null) TypeName() string             { return "null" }
+func (Null) String() string               { return "null" }
+func (Null) Truthy() bool                 { return false }
+func (Null) MarshalJSON() ([]byte, error) { return []byte("null"), nil }
+func (Null) Equal(other Value) bool {
+	_, ok := other.(Null)
+	return ok
+}
+
+// Display: отсутствие результата не выводится
+func (Null) Display(base int) []string { return nil }
+
+// encodeElement записывает элемент списка или словаря в файл состояния. Числа, строки,
+// логические значения, списки и null записываются как в JSON, остальные — с именем типа:
+// {"type": "map", "value": {...}}. Так любой JSON-объект в записи — значение с именем
+// типа, и словарь пользователя с ключами type и value не спутать с ним.
+func encodeElement(v Value) ([]byte, error) {
+	switch v.(type) {
+	case Number, Integer, String, Bool, List, Null:
+		return v.MarshalJSON()
+	}
+	return EncodeValue(v)
+}
+
+// decodeElement читает элемент, записанный encodeElement
+func decodeElement(data []byte) (Value, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) > 0 && data[0] == '[':
+		return decodeList(data)
+	case len(data) > 0 && data[0] == '{':
+		return DecodeValue(data)
+	}
+	return decodeScalar(data)
+}
+
+// encodeJSON записывает значение обычным JSON, без имён типов: словари — объектами,
+// списки — массивами, остальные значения — так же, как в файле состояния
+func encodeJSON(v Value) ([]byte, error) {
+	switch val := v.(type) {
+	case List:
+		return val.marshal(encodeJSON)
+	case Map:
+		return val.marshal(encodeJSON)
+	}
+	return v.MarshalJSON()
+}
+
+// decodeJSON читает обычный JSON: объекты становятся словарями, массивы — списками
+func decodeJSON(data []byte) (Value, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) > 0 && data[0] == '[':
+		return unmarshalList(data, decodeJSON)
+	case len(data) > 0 && data[0] == '{':
+		return unmarshalMap(data, decodeJSON)
+	}
+	return decodeScalar(data)
+}
+
+// decodeScalar читает строку, число, логическое значение или null. Целые числа
+// становятся Integer, числа с точкой или порядком — Number, как и в литералах.
+func decodeScalar(data []byte) (Value, error) {
+	if len(data) == 0 {
+		return nil, errors.New("пустое значение")
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(data, &s)
+		return String(s), err
+	case 't', 'f':
+		var b bool
+		err := json.Unmarshal(data, &b)
+		return Bool(b), err
+	case 'n':
+		return Null{}, nil
+	}
+	if !bytes.ContainsAny(data, ".eE") {
+		if n, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			return Integer(n), nil
+		}
+	}
+	return decodeNumber(data)
+}
+
+// === Величины и время ===
+
+func (q Quantity) TypeName() string { return "quantity" }
+func (q Quantity) String() string   { return FormatQuantity(q) }
+func (q Quantity) Truthy() bool     { return q.Value != 0 }
+func (q Quantity) Equal(other Value) bool {
+	return orderedEqual(q, other)
+}
+
+// MarshalJSON записывает величину так, как она вводится: "5.3 km"
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(formatQuantity(q, -1))
+}
+
+func decodeQuantity(data []byte) (Value, error) {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	return ParseQuantity(text)
+}
+
+func (t Time) TypeName() string { return "time" }
+func (t Time) String() string   { return FormatTime(time.Time(t)) }
+func (t Time) Truthy() bool     { return !time.Time(t).IsZero() }
+func (t Time) Equal(other Value) bool {
+	return orderedEqual(t, other)
+}
+
+// MarshalJSON записывает момент в RFC 3339 и название пояса:
+// "2026-10-16T09:00:00+03:00 Europe/Moscow" — смещения недостаточно для перехода на летнее время.
+// Вместо Local записывается название местного пояса: на другой машине Local означает другой пояс
+func (t Time) MarshalJSON() ([]byte, error) {
+	tt := time.Time(t)
+	text := tt.Format(time.RFC3339Nano)
+	if zone := zoneName(tt.Location()); zone != "" {
+		text += " " + zone
+	}
+	return json.Marshal(text)
+}
+
+// zoneName — название пояса из базы IANA или пустая строка, если его нет
+// (пояс с фиксированным смещением); тогда при чтении остаётся смещение из записи
+func zoneName(loc *time.Location) string {
+	if loc == time.Local {
+		return localZone()
+	}
+	if _, err := time.LoadLocation(loc.String()); err != nil {
+		return ""
+	}
+	return loc.String()
+}
+
+// localZone определяет название местного пояса по $TZ или ссылке /etc/localtime
+var localZone = sync.OnceValue(func() string {
+	name, set := os.LookupEnv("TZ")
+	name = strings.TrimPrefix(name, ":")
+	switch {
+	case set && name == "":
+		name = "UTC" // так пустую $TZ понимает и пакет time
+	case !set:
+		if target, err := os.Readlink("/etc/localtime"); err == nil {
+			if _, zone, ok := strings.Cut(target, "zoneinfo/"); ok {
+				name = zone
+			}
+		}
+	}
+	if name == "" || name == "Local" {
+		return ""
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
+})
+
+// decodeTime читает запись Time.MarshalJSON; если пояс не записан или неизвестен,
+// остаётся фиксированное смещение из записи RFC 3339. Local из старых файлов
+// тоже не используется: он означал пояс машины, на которой файл сохранили
+func decodeTime(data []byte) (Value, error) {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	stamp, zone, _ := strings.Cut(text, " ")
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная дата %q", text)
+	}
+	if zone != "" && zone != "Local" {
+		if loc, err := time.LoadLocation(zone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	return Time(t), nil
+}
+
+func (d Duration) TypeName() string { return "duration" }
+func (d Duration) String() string   { return FormatDuration(time.Duration(d)) }
+func (d Duration) Truthy() bool     { return d != 0 }
+func (d Duration) Equal(other Value) bool {
+	return orderedEqual(d, other)
+}
+
+// MarshalJSON записывает длительность в наносекундах
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(d), 10)), nil
+}
+
+// === Результаты, которые не хранятся в переменных ===
+
+func (p Plot) TypeName() string { return "plot" }
+func (p Plot) String() string   { return FormatPlot(p) }
+func (p Plot) Truthy() bool     { return len(p.Series) > 0 }
+func (p Plot) Equal(other Value) bool {
+	o, ok := other.(Plot)
+	return ok && p.String() == o.String() && p.File == o.File
+}
+
+// MarshalJSON записывает выборки графика; точки, где функция не определена, — null
+func (p Plot) MarshalJSON() ([]byte, error) {
+	type point struct {
+		X float64  `json:"x"`
+		Y *float64 `json:"y"`
+	}
+	type series struct {
+		Label  string  `json:"label"`
+		Points []point `json:"points"`
+	}
+	out := make([]series, len(p.Series))
+	for i, s := range p.Series {
+		out[i] = series{Label: s.Label, Points: make([]point, len(s.X))}
+		for j := range s.X {
+			out[i].Points[j].X = s.X[j]
+			if y := s.Y[j]; !math.IsNaN(y) && !math.IsInf(y, 0) {
+				out[i].Points[j].Y = &y
+			}
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (h History) TypeName() string { return "history" }
+func (h History) Truthy() bool     { return len(h) > 0 }
+func (h History) String() string   { return strings.Join(h, "\n") }
+func (h History) Equal(other Value) bool {
+	o, ok := other.(History)
+	return ok && slices.Equal(h, o)
+}
+func (h History) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string(h))
+}
+
+// Display выводит команды с номерами, по одной на строке
+func (h History) Display(base int) []string {
+	if len(h) == 0 {
+		return []string{"История пуста."}
+	}
+	lines := []string{"Последние команды:"}
+	for i, cmd := range h {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, cmd))
+	}
+	return lines
+}
+
+// numberEqual сравнивает число с другим значением по правилам ==: 2 == 2.0 == 2+0i
+func numberEqual(n, other Value) bool {
+	if z, ok := other.(Complex); ok {
+		return z.Equal(n)
+	}
+	return orderedEqual(n, other)
+}
+
+var valueBuiltins = map[string]*Builtin{
+	// type(x) — имя типа значения: "number", "list", "map"...
+	"type": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		return String(args[0].TypeName()), nil
+	}},
+	// json(текст) — значение из JSON: объекты становятся словарями, массивы — списками
+	"json": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		text, err := stringArg("json", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(text)) {
+			return nil, errors.New("json: некорректный JSON")
+		}
+		v, err := decodeJSON([]byte(text))
+		if err != nil {
+			return nil, fmt.Errorf("json: %v", err)
+		}
+		return v, nil
+	}},
+	// tojson(x) — запись значения в JSON: словари — объектами, списки — массивами
+	"tojson": {MinArgs: 1, MaxArgs: 1, Fn: func(args []Value) (Value, error) {
+		data, err := encodeJSON(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("tojson: %v", err)
+		}
+		return String(data), nil
+	}},
+}
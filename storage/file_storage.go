@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+
+	"calculator/core"
 )
 
 type FileStorage struct {
@@ -10,50 +14,123 @@ type FileStorage struct {
 }
 
 type State struct {
-	Variables map[string]float64 `json:"variables"`
-	StringVariables map[string]string `json:"string_variables"` // ← новое поле
-	History   []string           `json:"history"`
+	Variables map[string]core.Value `json:"-"`         // в файле — {"type": "number", "value": 2.5}, см. MarshalJSON
+	Functions map[string]string     `json:"functions"` // имя → текст определения
+	History   []string              `json:"history"`
+}
+
+// legacyVariables — поля файлов прежнего формата, где переменные каждого типа
+// хранились отдельно, и типы их значений
+var legacyVariables = map[string]string{
+	"variables":          "number",
+	"string_variables":   "string",
+	"bool_variables":     "bool",
+	"int_variables":      "integer",
+	"complex_variables":  "complex",
+	"list_variables":     "list",
+	"unit_variables":     "quantity",
+	"time_variables":     "time",
+	"duration_variables": "duration",
+	"big_variables":      "rational",
+}
+
+type plainState State
+
+// stateJSON — формат файла: переменные записываются вместе с именем типа
+type stateJSON struct {
+	*plainState
+	Variables map[string]json.RawMessage `json:"variables"`
+}
+
+func (s *State) MarshalJSON() ([]byte, error) {
+	vars := make(map[string]json.RawMessage, len(s.Variables))
+	for name, v := range s.Variables {
+		data, err := core.EncodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("переменная %s: %v", name, err)
+		}
+		vars[name] = data
+	}
+	return json.Marshal(stateJSON{plainState: (*plainState)(s), Variables: vars})
+}
+
+func (s *State) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainState)(s)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	s.Variables = make(map[string]core.Value)
+	for field, typeName := range legacyVariables {
+		raw, ok := fields[field]
+		if !ok {
+			continue
+		}
+		var vars map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &vars); err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+		for name, entry := range vars {
+			v, err := decodeVariable(typeName, entry)
+			if err != nil {
+				return fmt.Errorf("переменная %s: %v", name, err)
+			}
+			s.Variables[name] = v
+		}
+	}
+	return nil
+}
+
+// decodeVariable читает запись с именем типа, а в файлах прежнего формата —
+// значение без него; тип тогда задаёт поле, в котором оно записано
+func decodeVariable(typeName string, data json.RawMessage) (core.Value, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return core.DecodeValue(data)
+	}
+	return core.DecodeTyped(typeName, data)
 }
 
 func NewFileStorage(filename string) *FileStorage {
 	return &FileStorage{filename: filename}
 }
 
-func (s *FileStorage) Load() (map[string]float64, map[string]string, []string, error) {
+func NewState() *State {
+	return &State{
+		Variables: make(map[string]core.Value),
+		Functions: make(map[string]string),
+		History:   []string{},
+	}
+}
+
+func (s *FileStorage) Load() (*State, error) {
 	file, err := os.Open(s.filename)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return make(map[string]float64), make(map[string]string), []string{}, nil
+			return NewState(), nil
 		}
-		return nil, nil, nil, err
+		return nil, err
 	}
 	defer file.Close()
 
 	var state State
 	if err := json.NewDecoder(file).Decode(&state); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
-	if state.Variables == nil {
-		state.Variables = make(map[string]float64)
-	}
-	if state.StringVariables == nil {
-		state.StringVariables = make(map[string]string)
+	if state.Functions == nil {
+		state.Functions = make(map[string]string)
 	}
 	if state.History == nil {
 		state.History = []string{}
 	}
 
-	return state.Variables, state.StringVariables, state.History, nil
+	return &state, nil
 }
 
-func (s *FileStorage) Save(vars map[string]float64, strVars map[string]string, history []string) error {
-	state := State{
-		Variables: vars,
-		StringVariables: strVars,
-		History:   history,
-	}
-
+func (s *FileStorage) Save(state *State) error {
 	file, err := os.Create(s.filename)
 	if err != nil {
 		return err
@@ -2,48 +2,153 @@ package ui
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+
+	"calculator/core"
 )
 
 type ConsoleUI struct {
 	scanner *bufio.Scanner
+	prompt  bool // выводить приглашение "> " (только для терминала)
+	line    int  // номер последней прочитанной строки
+	base    int  // система счисления для вывода целых результатов
+
+	outputDir string       // каталог для файлов export(...)
+	chart     ChartOptions // оформление сохраняемых графиков
 }
 
 func NewConsoleUI() *ConsoleUI {
+	return NewReaderUI(os.Stdin, isTerminal(os.Stdin))
+}
+
+// NewReaderUI читает команды из произвольного источника, например из файла скрипта
+func NewReaderUI(r io.Reader, prompt bool) *ConsoleUI {
 	return &ConsoleUI{
-		scanner: bufio.NewScanner(os.Stdin),
+		scanner:   bufio.NewScanner(r),
+		prompt:    prompt,
+		base:      10,
+		outputDir: ".",
+		chart:     ChartOptions{Grid: true, Legend: true},
 	}
 }
 
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Interactive сообщает, что команды вводит человек в терминале
+func (c *ConsoleUI) Interactive() bool {
+	return c.prompt
+}
+
+// Line возвращает номер последней прочитанной строки
+func (c *ConsoleUI) Line() int {
+	return c.line
+}
+
 func (c *ConsoleUI) ReadCommand() (string, error) {
-	fmt.Print("> ")
+	return c.readLine("> ")
+}
+
+// ReadContinuation дочитывает продолжение многострочной команды
+func (c *ConsoleUI) ReadContinuation() (string, error) {
+	return c.readLine("... ")
+}
+
+func (c *ConsoleUI) readLine(prompt string) (string, error) {
+	if c.prompt {
+		fmt.Print(prompt)
+	}
 	if !c.scanner.Scan() {
-		return "", c.scanner.Err()
+		if err := c.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
 	}
+	c.line++
 	return strings.TrimSpace(c.scanner.Text()), nil
 }
 
-func (c *ConsoleUI) PrintResult(result float64) {
-	fmt.Println(result)
+// SetBase задаёт систему счисления для вывода целых чисел: 2, 8, 10 или 16
+func (c *ConsoleUI) SetBase(base int) error {
+	switch base {
+	case 2, 8, 10, 16:
+		c.base = base
+		return nil
+	default:
+		return fmt.Errorf("неподдерживаемая система счисления: %d (допустимы 2, 8, 10, 16)", base)
+	}
+}
+
+// PrintValue выводит результат команды. Графики рисуются или сохраняются в файл,
+// значения с core.Displayer выводятся своими строками (целые числа — в выбранной
+// системе счисления, матрицы — по строкам), остальные — записью Value.String.
+func (c *ConsoleUI) PrintValue(v core.Value) error {
+	if plot, ok := v.(core.Plot); ok {
+		return c.printPlot(plot)
+	}
+	if d, ok := v.(core.Displayer); ok {
+		for _, line := range d.Display(c.base) {
+			fmt.Println(line)
+		}
+		return nil
+	}
+	fmt.Println(v.String())
+	return nil
+}
+
+// printPlot рисует график в терминале или сохраняет его в файл, заданный export
+func (c *ConsoleUI) printPlot(plot core.Plot) error {
+	if plot.File == "" {
+		c.PrintPlot(plot)
+		return nil
+	}
+	path, err := c.ExportPlot(plot)
+	if err != nil {
+		return err
+	}
+	fmt.Println("график сохранён: " + path)
+	return nil
 }
 
 func (c *ConsoleUI) PrintError(err error) {
-	fmt.Printf("Ошибка: %v\n", err)
+	fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+	var parseErr *core.ParseError
+	if errors.As(err, &parseErr) {
+		fmt.Fprint(os.Stderr, caret(parseErr))
+	}
 }
 
-func (c *ConsoleUI) PrintHistory(history []string) {
-	if len(history) == 0 {
-		fmt.Println("История пуста.")
-		return
+// caret повторяет строку ввода и подчёркивает место ошибки: ^~~~
+func caret(e *core.ParseError) string {
+	line, from, to := e.SourceLine()
+	var pad strings.Builder
+	for _, r := range []rune(line)[:from] {
+		// Табуляции сохраняем, чтобы отметка встала под нужный символ
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
 	}
-	fmt.Println("Последние команды:")
-	for i, cmd := range history {
-		fmt.Printf("%d. %s\n", i+1, cmd)
+	width := to - from
+	if width < 1 {
+		width = 1
 	}
+	return "  " + line + "\n  " + pad.String() + "^" + strings.Repeat("~", width-1) + "\n"
 }
 
-func (c *ConsoleUI) PrintStringResult(result string) {
-	fmt.Println(result)
-}
\ No newline at end of file
+// PrintHistory выводит историю команд, например при запуске
+func (c *ConsoleUI) PrintHistory(history []string) {
+	for _, line := range core.History(history).Display(c.base) {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,404 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"calculator/core"
+)
+
+// ChartOptions — оформление графиков, сохраняемых в файл (команда :chart)
+type ChartOptions struct {
+	Title  string
+	Grid   bool
+	Legend bool
+}
+
+const (
+	chartWidth  = 800
+	chartHeight = 500
+)
+
+// Цвета серий в файлах — те же, что в терминале
+var chartColors = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff}, // синий
+	{0xd6, 0x27, 0x28, 0xff}, // красный
+	{0x2c, 0xa0, 0x2c, 0xff}, // зелёный
+	{0x94, 0x67, 0xbd, 0xff}, // пурпурный
+	{0xe3, 0xa0, 0x08, 0xff}, // жёлтый
+	{0x17, 0xbe, 0xcf, 0xff}, // голубой
+}
+
+var (
+	chartInk  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartGrid = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	chartAxis = color.RGBA{0x99, 0x99, 0x99, 0xff}
+)
+
+// SetOutputDir задаёт каталог для файлов export(...)
+func (c *ConsoleUI) SetOutputDir(dir string) {
+	c.outputDir = dir
+}
+
+// Chart возвращает текущее оформление графиков
+func (c *ConsoleUI) Chart() ChartOptions {
+	return c.chart
+}
+
+// SetChart меняет оформление графиков
+func (c *ConsoleUI) SetChart(options ChartOptions) {
+	c.chart = options
+}
+
+// ExportPlot сохраняет график в SVG или PNG в каталог вывода и возвращает путь к файлу
+func (c *ConsoleUI) ExportPlot(p core.Plot) (string, error) {
+	path, err := c.outputPath(p.File)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		data = renderSVG(p, c.chart)
+	case ".png":
+		data, err = renderPNG(p, c.chart)
+	default:
+		err = fmt.Errorf("неподдерживаемый формат файла: %s", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// outputPath переводит имя файла в путь внутри каталога вывода.
+// Абсолютные пути и пути, выходящие за каталог ("../x.svg"), запрещены.
+func (c *ConsoleUI) outputPath(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("абсолютные пути запрещены: %s", name)
+	}
+	dir := filepath.Clean(c.outputDir)
+	path := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("путь выходит за пределы каталога вывода: %s", name)
+	}
+	return path, nil
+}
+
+// chartLayout переводит координаты графика в пиксели и подбирает деления осей;
+// charWidth — ширина символа подписи, от неё зависит левое поле
+type chartLayout struct {
+	plot                     core.Plot
+	yMin, yMax               float64
+	left, top, right, bottom float64
+	xTicks, yTicks           []float64
+}
+
+func newChartLayout(p core.Plot, options ChartOptions, charWidth, lineHeight float64) chartLayout {
+	// Поля в 5% по вертикали, чтобы линии не сливались с рамкой
+	yMin, yMax := p.YRange()
+	pad := (yMax - yMin) * 0.05
+	l := chartLayout{plot: p, yMin: yMin - pad, yMax: yMax + pad}
+	l.xTicks = niceTicks(p.XMin, p.XMax)
+	l.yTicks = niceTicks(l.yMin, l.yMax)
+
+	labelWidth := 0
+	for _, t := range l.yTicks {
+		labelWidth = max(labelWidth, len(formatTick(t)))
+	}
+	l.left = float64(labelWidth)*charWidth + 16
+	l.right = chartWidth - 20
+	l.top = 20
+	if options.Title != "" {
+		l.top += lineHeight * 1.5
+	}
+	l.bottom = chartHeight - lineHeight - 16
+	return l
+}
+
+func (l chartLayout) px(x float64) float64 {
+	return l.left + (x-l.plot.XMin)/(l.plot.XMax-l.plot.XMin)*(l.right-l.left)
+}
+
+func (l chartLayout) py(y float64) float64 {
+	return l.bottom - (y-l.yMin)/(l.yMax-l.yMin)*(l.bottom-l.top)
+}
+
+// polylines делит серию на ломаные: точки вне диапазона и разрывы функции прерывают линию
+func (l chartLayout) polylines(s core.Series) [][][2]float64 {
+	var lines [][][2]float64
+	var current [][2]float64
+	for i, y := range s.Y {
+		if math.IsNaN(y) || math.IsInf(y, 0) || y < l.yMin || y > l.yMax {
+			if len(current) > 0 {
+				lines = append(lines, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, [2]float64{l.px(s.X[i]), l.py(y)})
+	}
+	if len(current) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// niceTicks выбирает около шести делений с шагом 1, 2 или 5 × 10^k
+func niceTicks(lo, hi float64) []float64 {
+	raw := (hi - lo) / 6
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude
+	for _, m := range []float64{2, 5, 10} {
+		if step >= raw {
+			break
+		}
+		step = m * magnitude
+	}
+	var ticks []float64
+	for t := math.Ceil(lo/step) * step; t <= hi+step*1e-9; t += step {
+		if math.Abs(t) < step*1e-9 {
+			t = 0
+		}
+		ticks = append(ticks, t)
+	}
+	return ticks
+}
+
+func renderSVG(p core.Plot, options ChartOptions) []byte {
+	const fontSize, charWidth = 12, 7.0
+	l := newChartLayout(p, options, charWidth, fontSize)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="%d">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight, fontSize)
+	b.WriteString(`<rect width="100%" height="100%" fill="white"/>` + "\n")
+
+	line := func(x1, y1, x2, y2 float64, c color.RGBA) {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", x1, y1, x2, y2, hexColor(c))
+	}
+	text := func(x, y float64, anchor, s string) {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`+"\n", x, y, anchor, html.EscapeString(s))
+	}
+
+	for _, t := range l.xTicks {
+		if options.Grid {
+			line(l.px(t), l.top, l.px(t), l.bottom, chartGrid)
+		}
+		text(l.px(t), l.bottom+fontSize+4, "middle", formatTick(t))
+	}
+	for _, t := range l.yTicks {
+		if options.Grid {
+			line(l.left, l.py(t), l.right, l.py(t), chartGrid)
+		}
+		text(l.left-6, l.py(t)+fontSize/3, "end", formatTick(t))
+	}
+	if l.yMin <= 0 && l.yMax >= 0 {
+		line(l.left, l.py(0), l.right, l.py(0), chartAxis)
+	}
+	if p.XMin <= 0 && p.XMax >= 0 {
+		line(l.px(0), l.top, l.px(0), l.bottom, chartAxis)
+	}
+	fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="none" stroke="%s"/>`+"\n",
+		l.left, l.top, l.right-l.left, l.bottom-l.top, hexColor(chartInk))
+
+	for i, s := range p.Series {
+		for _, pts := range l.polylines(s) {
+			coords := make([]string, len(pts))
+			for k, pt := range pts {
+				coords[k] = fmt.Sprintf("%.1f,%.1f", pt[0], pt[1])
+			}
+			fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`+"\n",
+				hexColor(seriesColor(i)), strings.Join(coords, " "))
+		}
+	}
+
+	if options.Title != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="%d">%s</text>`+"\n",
+			chartWidth/2, 24, fontSize*4/3, html.EscapeString(options.Title))
+	}
+	if options.Legend {
+		x := l.right - legendWidth(p, charWidth) - 40
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%d" fill="white" stroke="%s"/>`+"\n",
+			x-6, l.top+6, l.right-x, len(p.Series)*(fontSize+6)+6, hexColor(chartGrid))
+		for i, s := range p.Series {
+			y := l.top + 8 + fontSize + float64(i)*(fontSize+6)
+			line(x, y-fontSize/3, x+20, y-fontSize/3, seriesColor(i))
+			text(x+26, y, "start", s.Label)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+func renderPNG(p core.Plot, options ChartOptions) ([]byte, error) {
+	const scale = 2 // глифы 5×7 увеличиваются вдвое
+	charWidth := float64((glyphWidth + 1) * scale)
+	l := newChartLayout(p, options, charWidth, glyphHeight*scale)
+
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	c := pngCanvas{img}
+
+	for _, t := range l.xTicks {
+		if options.Grid {
+			c.line(l.px(t), l.top, l.px(t), l.bottom, chartGrid, 1)
+		}
+		label := formatTick(t)
+		c.text(l.px(t)-textWidth(label, scale)/2, l.bottom+8, label, scale, chartInk)
+	}
+	for _, t := range l.yTicks {
+		if options.Grid {
+			c.line(l.left, l.py(t), l.right, l.py(t), chartGrid, 1)
+		}
+		label := formatTick(t)
+		c.text(l.left-8-textWidth(label, scale), l.py(t)-glyphHeight*scale/2, label, scale, chartInk)
+	}
+	if l.yMin <= 0 && l.yMax >= 0 {
+		c.line(l.left, l.py(0), l.right, l.py(0), chartAxis, 1)
+	}
+	if p.XMin <= 0 && p.XMax >= 0 {
+		c.line(l.px(0), l.top, l.px(0), l.bottom, chartAxis, 1)
+	}
+	c.line(l.left, l.top, l.right, l.top, chartInk, 1)
+	c.line(l.right, l.top, l.right, l.bottom, chartInk, 1)
+	c.line(l.right, l.bottom, l.left, l.bottom, chartInk, 1)
+	c.line(l.left, l.bottom, l.left, l.top, chartInk, 1)
+
+	for i, s := range p.Series {
+		for _, pts := range l.polylines(s) {
+			for k := 1; k < len(pts); k++ {
+				c.line(pts[k-1][0], pts[k-1][1], pts[k][0], pts[k][1], seriesColor(i), 2)
+			}
+			if len(pts) == 1 {
+				c.line(pts[0][0], pts[0][1], pts[0][0], pts[0][1], seriesColor(i), 3)
+			}
+		}
+	}
+
+	if options.Title != "" {
+		c.text(chartWidth/2-textWidth(options.Title, scale)/2, 14, options.Title, scale, chartInk)
+	}
+	if options.Legend {
+		x := l.right - legendWidth(p, charWidth) - 40
+		bottom := l.top + 12 + float64(len(p.Series))*(glyphHeight*scale+8)
+		c.fill(x-6, l.top+6, l.right-10, bottom, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		for i, s := range p.Series {
+			y := l.top + 14 + float64(i)*(glyphHeight*scale+8)
+			c.line(x, y+glyphHeight*scale/2, x+20, y+glyphHeight*scale/2, seriesColor(i), 2)
+			c.text(x+26, y, s.Label, scale, chartInk)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// pngCanvas рисует линии и текст прямо в пикселях изображения
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+// line — отрезок толщиной width пикселей (алгоритм Брезенхэма)
+func (c pngCanvas) line(fx0, fy0, fx1, fy1 float64, col color.RGBA, width int) {
+	x0, y0 := int(math.Round(fx0)), int(math.Round(fy0))
+	x1, y1 := int(math.Round(fx1)), int(math.Round(fy1))
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	err := dx + dy
+	for {
+		for i := 0; i < width; i++ {
+			for j := 0; j < width; j++ {
+				c.img.SetRGBA(x0+i-width/2, y0+j-width/2, col)
+			}
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// fill закрашивает прямоугольник
+func (c pngCanvas) fill(x0, y0, x1, y1 float64, col color.RGBA) {
+	for y := int(y0); y <= int(y1); y++ {
+		for x := int(x0); x <= int(x1); x++ {
+			c.img.SetRGBA(x, y, col)
+		}
+	}
+	c.line(x0, y0, x1, y0, chartGrid, 1)
+	c.line(x1, y0, x1, y1, chartGrid, 1)
+	c.line(x1, y1, x0, y1, chartGrid, 1)
+	c.line(x0, y1, x0, y0, chartGrid, 1)
+}
+
+// text пишет строку растровым шрифтом; (x, y) — левый верхний угол
+func (c pngCanvas) text(x, y float64, s string, scale int, col color.RGBA) {
+	left, top := int(math.Round(x)), int(math.Round(y))
+	for i, r := range []rune(s) {
+		g := glyph(r)
+		for gy := 0; gy < glyphHeight; gy++ {
+			for gx := 0; gx < glyphWidth; gx++ {
+				if g[gy]&(1<<(glyphWidth-1-gx)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						c.img.SetRGBA(left+(i*(glyphWidth+1)+gx)*scale+dx, top+gy*scale+dy, col)
+					}
+				}
+			}
+		}
+	}
+}
+
+func textWidth(s string, scale int) float64 {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return float64((n*(glyphWidth+1) - 1) * scale)
+}
+
+func legendWidth(p core.Plot, charWidth float64) float64 {
+	width := 0
+	for _, s := range p.Series {
+		width = max(width, len([]rune(s.Label)))
+	}
+	return float64(width) * charWidth
+}
+
+func seriesColor(i int) color.RGBA {
+	return chartColors[i%len(chartColors)]
+}
+
+func hexColor(c color.RGBA) string {
+	return "#" + strconv.FormatUint(uint64(c.R)<<16|uint64(c.G)<<8|uint64(c.B)|1<<24, 16)[1:]
+}
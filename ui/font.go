@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Растровый шрифт 5×7 для подписей PNG: в стандартной библиотеке шрифтов нет.
+// Строчные кириллические буквы рисуются заглавными, похожие на латиницу — латинскими.
+var fontPatterns = map[rune]string{
+	' ':  "..... ..... ..... ..... ..... ..... .....",
+	'!':  "..#.. ..#.. ..#.. ..#.. ..#.. ..... ..#..",
+	'"':  ".#.#. .#.#. ..... ..... ..... ..... .....",
+	'#':  ".#.#. .#.#. ##### .#.#. ##### .#.#. .#.#.",
+	'%':  "##... ##..# ...#. ..#.. .#... #..## ...##",
+	'\'': "..#.. ..#.. ..... ..... ..... ..... .....",
+	'(':  "...#. ..#.. .#... .#... .#... ..#.. ...#.",
+	')':  ".#... ..#.. ...#. ...#. ...#. ..#.. .#...",
+	'*':  "..... ..#.. #.#.# .###. #.#.# ..#.. .....",
+	'+':  "..... ..#.. ..#.. ##### ..#.. ..#.. .....",
+	',':  "..... ..... ..... ..... .##.. ..#.. .#...",
+	'-':  "..... ..... ..... ##### ..... ..... .....",
+	'.':  "..... ..... ..... ..... ..... .##.. .##..",
+	'/':  "..... ....# ...#. ..#.. .#... #.... .....",
+	'0':  ".###. #...# #..## #.#.# ##..# #...# .###.",
+	'1':  "..#.. .##.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'2':  ".###. #...# ....# ...#. ..#.. .#... #####",
+	'3':  "##### ...#. ..#.. ...#. ....# #...# .###.",
+	'4':  "...#. ..##. .#.#. #..#. ##### ...#. ...#.",
+	'5':  "##### #.... ####. ....# ....# #...# .###.",
+	'6':  "..##. .#... #.... ####. #...# #...# .###.",
+	'7':  "##### ....# ...#. ..#.. .#... .#... .#...",
+	'8':  ".###. #...# #...# .###. #...# #...# .###.",
+	'9':  ".###. #...# #...# .#### ....# ...#. .##..",
+	':':  "..... .##.. .##.. ..... .##.. .##.. .....",
+	';':  "..... .##.. .##.. ..... .##.. ..#.. .#...",
+	'<':  "...#. ..#.. .#... #.... .#... ..#.. ...#.",
+	'=':  "..... ..... ##### ..... ##### ..... .....",
+	'>':  ".#... ..#.. ...#. ....# ...#. ..#.. .#...",
+	'?':  ".###. #...# ....# ...#. ..#.. ..... ..#..",
+	'[':  ".###. .#... .#... .#... .#... .#... .###.",
+	']':  ".###. ...#. ...#. ...#. ...#. ...#. .###.",
+	'^':  "..#.. .#.#. #...# ..... ..... ..... .....",
+	'_':  "..... ..... ..... ..... ..... ..... #####",
+	'|':  "..#.. ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'A':  ".###. #...# #...# ##### #...# #...# #...#",
+	'B':  "####. #...# #...# ####. #...# #...# ####.",
+	'C':  ".###. #...# #.... #.... #.... #...# .###.",
+	'D':  "###.. #..#. #...# #...# #...# #..#. ###..",
+	'E':  "##### #.... #.... ####. #.... #.... #####",
+	'F':  "##### #.... #.... ####. #.... #.... #....",
+	'G':  ".###. #...# #.... #.### #...# #...# .####",
+	'H':  "#...# #...# #...# ##### #...# #...# #...#",
+	'I':  ".###. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'J':  "..### ...#. ...#. ...#. ...#. #..#. .##..",
+	'K':  "#...# #..#. #.#.. ##... #.#.. #..#. #...#",
+	'L':  "#.... #.... #.... #.... #.... #.... #####",
+	'M':  "#...# ##.## #.#.# #.#.# #...# #...# #...#",
+	'N':  "#...# #...# ##..# #.#.# #..## #...# #...#",
+	'O':  ".###. #...# #...# #...# #...# #...# .###.",
+	'P':  "####. #...# #...# ####. #.... #.... #....",
+	'Q':  ".###. #...# #...# #...# #.#.# #..#. .##.#",
+	'R':  "####. #...# #...# ####. #.#.. #..#. #...#",
+	'S':  ".#### #.... #.... .###. ....# ....# ####.",
+	'T':  "##### ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'U':  "#...# #...# #...# #...# #...# #...# .###.",
+	'V':  "#...# #...# #...# #...# #...# .#.#. ..#..",
+	'W':  "#...# #...# #...# #.#.# #.#.# #.#.# .#.#.",
+	'X':  "#...# #...# .#.#. ..#.. .#.#. #...# #...#",
+	'Y':  "#...# #...# .#.#. ..#.. ..#.. ..#.. ..#..",
+	'Z':  "##### ....# ...#. ..#.. .#... #.... #####",
+	'a':  "..... ..... .###. ....# .#### #...# .####",
+	'b':  "#.... #.... #.##. ##..# #...# #...# ####.",
+	'c':  "..... ..... .###. #.... #.... #...# .###.",
+	'd':  "....# ....# .##.# #..## #...# #...# .####",
+	'e':  "..... ..... .###. #...# ##### #.... .###.",
+	'f':  "..##. .#..# .#... ###.. .#... .#... .#...",
+	'g':  "..... .#### #...# #...# .#### ....# .###.",
+	'h':  "#.... #.... #.##. ##..# #...# #...# #...#",
+	'i':  "..#.. ..... .##.. ..#.. ..#.. ..#.. .###.",
+	'j':  "...#. ..... ..##. ...#. ...#. #..#. .##..",
+	'k':  "#.... #.... #..#. #.#.. ##... #.#.. #..#.",
+	'l':  ".##.. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'm':  "..... ..... ##.#. #.#.# #.#.# #...# #...#",
+	'n':  "..... ..... #.##. ##..# #...# #...# #...#",
+	'o':  "..... ..... .###. #...# #...# #...# .###.",
+	'p':  "..... ..... ####. #...# ####. #.... #....",
+	'q':  "..... ..... .##.# #..## .#### ....# ....#",
+	'r':  "..... ..... #.##. ##..# #.... #.... #....",
+	's':  "..... ..... .###. #.... .###. ....# ####.",
+	't':  ".#... .#... ###.. .#... .#... .#..# ..##.",
+	'u':  "..... ..... #...# #...# #...# #..## .##.#",
+	'v':  "..... ..... #...# #...# #...# .#.#. ..#..",
+	'w':  "..... ..... #...# #...# #.#.# #.#.# .#.#.",
+	'x':  "..... ..... #...# .#.#. ..#.. .#.#. #...#",
+	'y':  "..... ..... #...# #...# .#### ....# .###.",
+	'z':  "..... ..... ##### ...#. ..#.. .#... #####",
+	'Б':  "##### #.... #.... ####. #...# #...# ####.",
+	'Г':  "##### #.... #.... #.... #.... #.... #....",
+	'Д':  "..##. .#.#. .#.#. .#.#. .#.#. ##### #...#",
+	'Ж':  "#.#.# #.#.# .###. ..#.. .###. #.#.# #.#.#",
+	'З':  ".###. #...# ....# ..##. ....# #...# .###.",
+	'И':  "#...# #...# #..## #.#.# ##..# #...# #...#",
+	'Й':  ".#.#. ..#.. #...# #..## #.#.# ##..# #...#",
+	'Л':  "..### .#..# .#..# .#..# .#..# .#..# #...#",
+	'П':  "##### #...# #...# #...# #...# #...# #...#",
+	'У':  "#...# #...# #...# .#### ....# #...# .###.",
+	'Ф':  "..#.. .###. #.#.# #.#.# #.#.# .###. ..#..",
+	'Ц':  "#..#. #..#. #..#. #..#. #..#. ##### ....#",
+	'Ч':  "#...# #...# #...# .#### ....# ....# ....#",
+	'Ш':  "#.#.# #.#.# #.#.# #.#.# #.#.# #.#.# #####",
+	'Щ':  "#.#.# #.#.# #.#.# #.#.# #.#.# ##### ....#",
+	'Ъ':  "##... .#... .#... .###. .#..# .#..# .###.",
+	'Ы':  "#...# #...# #...# ###.# #..## #..## ###.#",
+	'Ь':  "#.... #.... #.... ####. #...# #...# ####.",
+	'Э':  ".###. #...# ....# ..### ....# #...# .###.",
+	'Ю':  "#..#. #.#.# #.#.# ###.# #.#.# #.#.# #..#.",
+	'Я':  ".#### #...# #...# .#### ..#.# .#..# #...#",
+}
+
+// Кириллические буквы, совпадающие по начертанию с латинскими
+var fontAliases = map[rune]rune{
+	'А': 'A', 'В': 'B', 'Е': 'E', 'Ё': 'E', 'К': 'K', 'М': 'M',
+	'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X',
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// font[r][y] — строка глифа, старший из пяти битов — левая точка
+var font = make(map[rune][glyphHeight]uint8)
+
+func init() {
+	for r, pattern := range fontPatterns {
+		var g [glyphHeight]uint8
+		for y, row := range strings.Fields(pattern) {
+			for x, ch := range row {
+				if ch == '#' {
+					g[y] |= 1 << (glyphWidth - 1 - x)
+				}
+			}
+		}
+		font[r] = g
+	}
+}
+
+// glyph находит начертание символа; неизвестные символы рисуются как '?'
+func glyph(r rune) [glyphHeight]uint8 {
+	if g, ok := font[r]; ok {
+		return g
+	}
+	upper := unicode.ToUpper(r)
+	if alias, ok := fontAliases[upper]; ok {
+		upper = alias
+	}
+	if g, ok := font[upper]; ok {
+		return g
+	}
+	return font['?']
+}
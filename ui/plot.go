@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"calculator/core"
+)
+
+const (
+	plotHeight   = 16  // строк графика; в каждом символе 4 точки по вертикали
+	minPlotWidth = 20  // символов; уже рисовать нет смысла
+	maxPlotWidth = 200 // символов; шире выборка PlotSamples не даёт деталей
+)
+
+// Цвета серий: синий, красный, зелёный, пурпурный, жёлтый, голубой
+var seriesColors = []string{"34", "31", "32", "35", "33", "36"}
+
+// Знаки серий без цвета: первая рисуется точками Брайля, остальные — своими символами
+var seriesMarkers = []string{"⣿", "•", "+", "x", "o", "#"}
+
+// PrintPlot рисует график символами Брайля (2×4 точки в символе) по ширине терминала
+func (c *ConsoleUI) PrintPlot(p core.Plot) {
+	fmt.Print(renderPlot(p, terminalWidth(), isTerminal(os.Stdout)))
+}
+
+// terminalWidth берёт ширину из $COLUMNS, затем у терминала; по умолчанию 80
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := terminalColumns(os.Stdout); n > 0 {
+		return n
+	}
+	return 80
+}
+
+// canvas — поле из символов Брайля; color хранит номер серии символа (0 — оси)
+type canvas struct {
+	cols, rows int
+	dots       [][]uint8
+	color      [][]int
+}
+
+func newCanvas(cols, rows int) *canvas {
+	c := &canvas{cols: cols, rows: rows, dots: make([][]uint8, rows), color: make([][]int, rows)}
+	for r := range c.dots {
+		c.dots[r] = make([]uint8, cols)
+		c.color[r] = make([]int, cols)
+	}
+	return c
+}
+
+// Биты точек символа Брайля: brailleBits[y][x] для точки (x, y) внутри символа
+var brailleBits = [4][2]uint8{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+func (c *canvas) set(x, y, series int) {
+	if x < 0 || y < 0 || x >= c.cols*2 || y >= c.rows*4 {
+		return
+	}
+	c.dots[y/4][x/2] |= brailleBits[y%4][x%2]
+	if series > 0 {
+		c.color[y/4][x/2] = series
+	}
+}
+
+// line соединяет две точки отрезком (алгоритм Брезенхэма)
+func (c *canvas) line(x0, y0, x1, y1, series int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	err := dx + dy
+	for {
+		c.set(x0, y0, series)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func renderPlot(p core.Plot, width int, colored bool) string {
+	yMin, yMax := p.YRange()
+	dotsY := plotHeight * 4
+	toY := func(y float64) int {
+		return int(math.Round((yMax - y) / (yMax - yMin) * float64(dotsY-1)))
+	}
+	labels := yAxisLabels(yMin, yMax, toY)
+	labelWidth := 0
+	for _, l := range labels {
+		labelWidth = max(labelWidth, len(l))
+	}
+	cols := min(max(width-labelWidth-2, minPlotWidth), maxPlotWidth)
+
+	c := newCanvas(cols, plotHeight)
+	dotsX := cols * 2
+	toX := func(x float64) int {
+		return int(math.Round((x - p.XMin) / (p.XMax - p.XMin) * float64(dotsX-1)))
+	}
+
+	// Оси x = 0 и y = 0 — пунктиром
+	if yMin <= 0 && yMax >= 0 {
+		for x, y := 0, toY(0); x < dotsX; x += 2 {
+			c.set(x, y, 0)
+		}
+	}
+	if p.XMin <= 0 && p.XMax >= 0 {
+		for x, y := toX(0), 0; y < dotsY; y += 2 {
+			c.set(x, y, 0)
+		}
+	}
+
+	for i, s := range p.Series {
+		prevX, prevY, connected := 0, 0, false
+		for k, y := range s.Y {
+			if math.IsNaN(y) || math.IsInf(y, 0) || y < yMin || y > yMax {
+				connected = false
+				continue
+			}
+			x, py := toX(s.X[k]), toY(y)
+			if connected {
+				c.line(prevX, prevY, x, py, i+1)
+			} else {
+				c.set(x, py, i+1)
+			}
+			prevX, prevY, connected = x, py, true
+		}
+	}
+
+	var b strings.Builder
+	for r := 0; r < plotHeight; r++ {
+		label, tick := labels[r], "┤"
+		if label == "" {
+			tick = "│"
+		}
+		b.WriteString(fmt.Sprintf("%*s%s", labelWidth, label, tick))
+		for col := 0; col < cols; col++ {
+			bits := c.dots[r][col]
+			if bits == 0 {
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteString(paint(cellGlyph(bits, c.color[r][col], len(p.Series), colored), c.color[r][col], colored))
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Repeat(" ", labelWidth) + "└" + strings.Repeat("─", cols) + "\n")
+	b.WriteString(strings.Repeat(" ", labelWidth+1) + xAxisLabels(p, cols, toX) + "\n")
+
+	for i, s := range p.Series {
+		b.WriteString(strings.Repeat(" ", labelWidth+1) + paint(cellGlyph(0xff, i+1, len(p.Series), colored), i+1, colored) + " " + s.Label + "\n")
+	}
+	return b.String()
+}
+
+// cellGlyph — символ клетки графика. Без цвета серии различаются знаками из seriesMarkers,
+// с цветом или при одной серии все рисуются точками Брайля
+func cellGlyph(bits uint8, series, count int, colored bool) string {
+	if colored || count < 2 || series < 2 {
+		return string(rune(0x2800 + int(bits)))
+	}
+	return seriesMarkers[(series-1)%len(seriesMarkers)]
+}
+
+// yAxisLabels подписывает верхнюю и нижнюю строки и ноль, если он в диапазоне, иначе — середину.
+// Строка подписи находится через toY, которым рисуются точки, поэтому подпись совпадает с линией.
+func yAxisLabels(yMin, yMax float64, toY func(float64) int) map[int]string {
+	last := plotHeight - 1
+	labels := map[int]string{0: formatTick(yMax), last: formatTick(yMin)}
+	if yMin < 0 && yMax > 0 {
+		if r := toY(0) / 4; r > 0 && r < last {
+			labels[r] = formatTick(0)
+			return labels
+		}
+	}
+	// Значение в середине строки: его точка попадает именно в эту строку
+	mid := plotHeight / 2
+	dotsY := float64(plotHeight*4 - 1)
+	labels[mid] = formatTick(yMax - (float64(mid*4)+1.5)/dotsY*(yMax-yMin))
+	return labels
+}
+
+// xAxisLabels подписывает левый и правый концы отрезка и ноль, если он между ними
+func xAxisLabels(p core.Plot, cols int, toX func(float64) int) string {
+	line := []rune(strings.Repeat(" ", cols))
+	place := func(text string, at int) {
+		at = min(max(at, 0), cols-len(text))
+		for i, r := range text {
+			if at+i >= 0 && at+i < cols {
+				line[at+i] = r
+			}
+		}
+	}
+	left, right := formatTick(p.XMin), formatTick(p.XMax)
+	place(left, 0)
+	place(right, cols-len(right))
+	if p.XMin < 0 && p.XMax > 0 {
+		at := toX(0) / 2
+		if at > len(left) && at+1 < cols-len(right) {
+			place("0", at)
+		}
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+func formatTick(v float64) string {
+	if math.Abs(v) < 1e-12 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// paint раскрашивает символ серии escape-последовательностью ANSI
+func paint(s string, series int, colored bool) string {
+	if !colored || series == 0 {
+		return s
+	}
+	return "\x1b[" + seriesColors[(series-1)%len(seriesColors)] + "m" + s + "\x1b[0m"
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
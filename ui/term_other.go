@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package ui
+
+import "os"
+
+// terminalColumns без поддержки ioctl: ширина берётся только из $COLUMNS
+func terminalColumns(f *os.File) int {
+	return 0
+}